@@ -0,0 +1,336 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// cliOutputJSON is the cliOutput for writing response bodies as JSON, one per line.
+	cliOutputJSON cliOutput = "json"
+	// cliOutputPrettyJSON is the cliOutput for writing response bodies as indented JSON.
+	//
+	// This is the default cliOutput if the request was given as a bare request.
+	cliOutputPrettyJSON cliOutput = "pretty-json"
+	// cliOutputText is the cliOutput for writing response bodies in the protobuf text format.
+	cliOutputText cliOutput = "text"
+
+	// cliInputJSON is the cliInput for requests given on stdin as JSON.
+	cliInputJSON cliInput = "json"
+	// cliInputBinary is the cliInput for requests given on stdin in the binary protobuf format.
+	cliInputBinary cliInput = "binary"
+)
+
+// cliOutput is how responses are written when a plugin is invoked directly from the
+// command line with `--plugin-output`.
+//
+// The empty cliOutput writes pluginrpcv1beta1.Responses, as expected by Clients.
+type cliOutput string
+
+func parseCLIOutput(value string) (cliOutput, error) {
+	switch output := cliOutput(value); output {
+	case cliOutputJSON, cliOutputPrettyJSON, cliOutputText:
+		return output, nil
+	default:
+		return "", fmt.Errorf(
+			"unknown output %q, must be one of %q, %q, or %q",
+			value,
+			cliOutputJSON,
+			cliOutputPrettyJSON,
+			cliOutputText,
+		)
+	}
+}
+
+// cliInput is how the request is read from stdin when a plugin is invoked directly from
+// the command line with `--plugin-input`.
+//
+// If a cliInput is given, stdin contains a bare request, that is, the request message
+// itself rather than a pluginrpcv1beta1.Request, without any frames. The empty cliInput
+// reads pluginrpcv1beta1.Requests, as sent by Clients, or bare requests in JSON.
+type cliInput string
+
+func parseCLIInput(value string) (cliInput, error) {
+	switch input := cliInput(value); input {
+	case cliInputJSON, cliInputBinary:
+		return input, nil
+	default:
+		return "", fmt.Errorf("unknown input %q, must be one of %q or %q", value, cliInputJSON, cliInputBinary)
+	}
+}
+
+// cliOptions are the options given on the command line after the args of a Procedure.
+//
+// Clients never give such options, so these are only set when a plugin is invoked directly.
+type cliOptions struct {
+	// requestFlags are the flags that set fields of the request, such as `--message=hello`.
+	requestFlags []string
+	input        cliInput
+	output       cliOutput
+}
+
+// parseCLIOptions parses the args given after the args of a Procedure.
+//
+// The `--plugin-input` and `--plugin-output` flags, with the given flag prefix, set the
+// input and output. All other args are request flags. Values must be given as part of the
+// flag, such as `--message=hello`, as whether the next arg is a value depends on the type
+// of the field.
+func parseCLIOptions(flagPrefix string, args []string) (*cliOptions, error) {
+	options := &cliOptions{}
+	inputFlag := fullFlag(flagPrefix, flagInputSuffix)
+	outputFlag := fullFlag(flagPrefix, flagOutputSuffix)
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		switch name {
+		case inputFlag:
+			input, err := parseCLIInput(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", inputFlag, err)
+			}
+			options.input = input
+		case outputFlag:
+			output, err := parseCLIOutput(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", outputFlag, err)
+			}
+			options.output = output
+		default:
+			options.requestFlags = append(options.requestFlags, arg)
+		}
+	}
+	return options, nil
+}
+
+type cliOptionsContextKey struct{}
+
+// withCLIOptions returns a context with the cliOptions of the call being served.
+func withCLIOptions(ctx context.Context, options *cliOptions) context.Context {
+	return context.WithValue(ctx, cliOptionsContextKey{}, options)
+}
+
+// cliOptionsFromContext returns the cliOptions of the call being served.
+//
+// If the context was not created by a Server, this returns empty cliOptions.
+func cliOptionsFromContext(ctx context.Context) *cliOptions {
+	options, ok := ctx.Value(cliOptionsContextKey{}).(*cliOptions)
+	if !ok || options == nil {
+		return &cliOptions{}
+	}
+	return options
+}
+
+// setRequestFlags sets the fields of the request from the request flags.
+//
+// Flags are named after fields, using either the proto or JSON name, with fields of
+// nested messages separated by dots, such as `--parent.child=value`. Values are parsed
+// as they would be within a JSON object, except that strings do not need to be quoted.
+// Repeated fields can be given more than once, and message and map fields can be given
+// as JSON objects. Boolean fields given without a value are set to true.
+//
+// The flags are merged into the request, so fields given on stdin are kept unless
+// overridden. Invalid flags result in an *Error with CodeInvalidArgument.
+func setRequestFlags(request any, requestFlags []string) error {
+	if len(requestFlags) == 0 {
+		return nil
+	}
+	requestMessage, err := toProtoMessage(request)
+	if err != nil {
+		return err
+	}
+	messageDescriptor := requestMessage.ProtoReflect().Descriptor()
+	jsonObject := make(map[string]any)
+	for _, requestFlag := range requestFlags {
+		if err := addRequestFlag(jsonObject, messageDescriptor, requestFlag); err != nil {
+			return NewError(CodeInvalidArgument, err)
+		}
+	}
+	data, err := json.Marshal(jsonObject)
+	if err != nil {
+		return err
+	}
+	flagsMessage := requestMessage.ProtoReflect().New().Interface()
+	if err := protojson.Unmarshal(data, flagsMessage); err != nil {
+		return NewErrorf(CodeInvalidArgument, "invalid flags: %w", err)
+	}
+	proto.Merge(requestMessage, flagsMessage)
+	return nil
+}
+
+// addRequestFlag adds the value of the request flag to the JSON object for the message.
+//
+// Values of the JSON object are either json.RawMessages for singular fields,
+// []json.RawMessages for repeated fields, or map[string]anys for nested messages
+// given field by field.
+func addRequestFlag(jsonObject map[string]any, messageDescriptor protoreflect.MessageDescriptor, requestFlag string) error {
+	if !strings.HasPrefix(requestFlag, "--") {
+		return fmt.Errorf("unknown arg %q", requestFlag)
+	}
+	path, value, hasValue := strings.Cut(strings.TrimPrefix(requestFlag, "--"), "=")
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fieldDescriptor := getFieldDescriptor(messageDescriptor, name)
+		if fieldDescriptor == nil {
+			return fmt.Errorf("unknown flag %q: %s has no field %q", requestFlag, messageDescriptor.FullName(), name)
+		}
+		key := string(fieldDescriptor.Name())
+		if i < len(names)-1 {
+			if fieldDescriptor.Message() == nil || fieldDescriptor.IsList() || fieldDescriptor.IsMap() {
+				return fmt.Errorf("invalid flag %q: %s is not a singular message field", requestFlag, fieldDescriptor.FullName())
+			}
+			existing, ok := jsonObject[key]
+			if !ok {
+				existing = make(map[string]any)
+				jsonObject[key] = existing
+			}
+			nestedJSONObject, ok := existing.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid flag %q: %s given more than once", requestFlag, fieldDescriptor.FullName())
+			}
+			jsonObject = nestedJSONObject
+			messageDescriptor = fieldDescriptor.Message()
+			continue
+		}
+		jsonValue, err := getRequestFlagJSONValue(fieldDescriptor, value, hasValue)
+		if err != nil {
+			return fmt.Errorf("invalid flag %q: %w", requestFlag, err)
+		}
+		if fieldDescriptor.IsList() {
+			existing, _ := jsonObject[key].([]json.RawMessage)
+			jsonObject[key] = append(existing, jsonValue)
+			continue
+		}
+		if _, ok := jsonObject[key]; ok {
+			return fmt.Errorf("invalid flag %q: %s given more than once", requestFlag, fieldDescriptor.FullName())
+		}
+		jsonObject[key] = jsonValue
+	}
+	return nil
+}
+
+// getFieldDescriptor returns the field with the given proto or JSON name, or nil if no
+// such field exists.
+func getFieldDescriptor(messageDescriptor protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := messageDescriptor.Fields()
+	if fieldDescriptor := fields.ByName(protoreflect.Name(name)); fieldDescriptor != nil {
+		return fieldDescriptor
+	}
+	return fields.ByJSONName(name)
+}
+
+// getRequestFlagJSONValue returns the JSON value for a single value of the field.
+func getRequestFlagJSONValue(fieldDescriptor protoreflect.FieldDescriptor, value string, hasValue bool) (json.RawMessage, error) {
+	if fieldDescriptor.Kind() == protoreflect.BoolKind {
+		if !hasValue {
+			return json.RawMessage("true"), nil
+		}
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", value)
+		}
+		return json.Marshal(boolValue)
+	}
+	if !hasValue {
+		return nil, fmt.Errorf("no value given for %s", fieldDescriptor.FullName())
+	}
+	switch {
+	case fieldDescriptor.IsMap(), fieldDescriptor.Message() != nil && !isJSONStringMessage(fieldDescriptor.Message()):
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("value for %s must be JSON", fieldDescriptor.FullName())
+		}
+		return json.RawMessage(value), nil
+	case fieldDescriptor.Kind() == protoreflect.EnumKind:
+		if _, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return json.RawMessage(value), nil
+		}
+	}
+	// All other values are given as JSON strings, which protojson accepts for numbers.
+	return json.Marshal(value)
+}
+
+// isJSONStringMessage returns true if the message is a well-known type whose JSON form is
+// a string, such as google.protobuf.Timestamp, so that values do not need to be quoted.
+func isJSONStringMessage(messageDescriptor protoreflect.MessageDescriptor) bool {
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp",
+		"google.protobuf.Duration",
+		"google.protobuf.FieldMask",
+		"google.protobuf.StringValue",
+		"google.protobuf.BytesValue",
+		"google.protobuf.Int64Value",
+		"google.protobuf.UInt64Value":
+		return true
+	default:
+		return false
+	}
+}
+
+// marshalCLIOutput marshals the response in the given cliOutput.
+func marshalCLIOutput(output cliOutput, response any) ([]byte, error) {
+	responseMessage, err := toProtoMessage(response)
+	if err != nil {
+		return nil, err
+	}
+	switch output {
+	case cliOutputJSON:
+		return protojson.Marshal(responseMessage)
+	case cliOutputPrettyJSON:
+		return protojson.MarshalOptions{Multiline: true}.Marshal(responseMessage)
+	case cliOutputText:
+		data, err := prototext.MarshalOptions{Multiline: true}.Marshal(responseMessage)
+		if err != nil {
+			return nil, err
+		}
+		// Multiline text ends with a newline, which is added when written.
+		return bytes.TrimSuffix(data, []byte("\n")), nil
+	default:
+		return nil, fmt.Errorf("unknown output: %q", output)
+	}
+}
+
+// unmarshalCLIInput unmarshals the bare request given on stdin in the given cliInput.
+//
+// Invalid requests result in an *Error with CodeInvalidArgument.
+func unmarshalCLIInput(input cliInput, data []byte, request any) error {
+	requestMessage, err := toProtoMessage(request)
+	if err != nil {
+		return err
+	}
+	switch input {
+	case cliInputJSON:
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+		err = protojson.Unmarshal(data, requestMessage)
+	case cliInputBinary:
+		err = proto.Unmarshal(data, requestMessage)
+	default:
+		return fmt.Errorf("unknown input: %q", input)
+	}
+	if err != nil {
+		return NewErrorf(CodeInvalidArgument, "invalid request: %w", err)
+	}
+	return nil
+}
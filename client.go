@@ -17,17 +17,26 @@ package pluginrpc
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 var (
 	defaultStderr = io.Discard
+
+	// errSpecIDMismatch is the underlying error when the Spec of a plugin changed since
+	// the Spec was retrieved by a Client.
+	errSpecIDMismatch = errors.New("plugin spec changed")
 )
 
 // Client is a client that calls plugins.
@@ -37,14 +46,32 @@ var (
 // Typically, Clients are not directly invoked. Instead, the generated code for a given
 // service will use a Client to call the Procedures that the service specifies.
 //
-// Clients will cache retrieved Protocols and Specs. If it is possible that a plugin will
-// change during the lifetime of a Client, it is the responsibility of the caller to
-// create a new Client. We may change this requirement in the future.
+// Clients will cache retrieved Protocols and Specs. Plugins that support spec IDs will
+// reject calls made with an outdated Spec, in which case the Client will invalidate its
+// cache. Unary calls are then automatically retried once, while streaming calls return an
+// *Error with CodeFailedPrecondition. The cache can also be explicitly invalidated with
+// InvalidateSpec. Errors retrieving the Protocol and Spec are never cached.
 type Client interface {
+	// Spec returns the Spec of the plugin.
+	//
+	// The Spec includes the name, version, and description of the plugin, if the plugin
+	// specified them.
+	Spec(ctx context.Context) (Spec, error)
+	// FileDescriptorSet returns the descriptors of the files that define the request and
+	// response types of the Procedures of the plugin, along with all of their dependencies.
+	//
+	// Every file comes after its dependencies. Plugins only return descriptors for Procedures
+	// with a MethodDescriptor, which includes all Procedures built by generated code. The
+	// method that a Procedure implements is named by its path, which is of the form
+	// "/package.Service/Method".
+	//
+	// If the plugin does not support reflection, this returns an *Error with CodeUnimplemented.
+	FileDescriptorSet(ctx context.Context) (*descriptorpb.FileDescriptorSet, error)
 	// Call calls the given Procedure.
 	//
 	// The request will be sent over stdin, with a response being sent on stdout.
-	// The response given will then be populated.
+	// The response given will then be populated. If the plugin returned a response alongside
+	// an error, the response is populated, and HasPartialResponse returns true for the error.
 	Call(
 		ctx context.Context,
 		procedurePath string,
@@ -52,6 +79,38 @@ type Client interface {
 		response any,
 		options ...CallOption,
 	) error
+	// CallServerStream calls the given server-streaming Procedure.
+	//
+	// The request will be sent over stdin, with a stream of responses being sent on stdout.
+	// The returned ResponseStream can be used to receive the responses as they arrive.
+	// The ResponseStream must be closed once the caller is done with it.
+	CallServerStream(
+		ctx context.Context,
+		procedurePath string,
+		request any,
+		options ...CallOption,
+	) (ResponseStream, error)
+	// CallClientStream calls the given client-streaming Procedure.
+	//
+	// Requests sent on the returned RequestStream will be sent over stdin as they are sent,
+	// with a response being sent on stdout once the RequestStream is closed.
+	CallClientStream(
+		ctx context.Context,
+		procedurePath string,
+		options ...CallOption,
+	) (RequestStream, error)
+	// InvalidateSpec invalidates the cached Protocol and Spec of the plugin.
+	//
+	// The Protocol and Spec will be retrieved again on the next call. If a persistent plugin
+	// process is running, the process is closed once all in-flight calls have completed, and
+	// a new process is launched on the next call.
+	InvalidateSpec()
+	// Close releases any resources held by the Client.
+	//
+	// If ClientWithPersistentProcess was specified, this closes the persistent plugin
+	// process, waiting for any in-flight calls to complete. The process is cancelled if it
+	// has not exited within a minute. Otherwise, this is a no-op.
+	Close() error
 }
 
 // NewClient returns a new Client for the given Runner.
@@ -71,6 +130,21 @@ func ClientWithStderr(stderr io.Writer) ClientOption {
 	}
 }
 
+// ClientWithLogHandler will result in log records emitted by the plugin via LoggerFromContext
+// being forwarded to the given slog.Handler.
+//
+// Each record has an attribute with the key "procedure" added, containing the path of the
+// Procedure being called. Log records are sent on the stderr of the plugin, and are removed
+// from the stderr propagated by ClientWithStderr and CallWithStderr. All other stderr output
+// is still propagated, including the stderr of plugins that do not support structured logging.
+//
+// The default is for plugins to write log records to stderr as text.
+func ClientWithLogHandler(logHandler slog.Handler) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.logHandler = logHandler
+	}
+}
+
 // ClientWithFlagPrefix adds a prefix to the `--plugin-protocol` and `--plugin-spec` flags.
 //
 // For example, if the prefix `foo` is given, the flags `--foo-plugin-protocol` and
@@ -81,19 +155,193 @@ func ClientWithFlagPrefix(flagPrefix string) ClientOption {
 	}
 }
 
+// ClientWithPersistentProcess will result in the Client launching the plugin once with
+// `--plugin-serve`, and multiplexing all calls over the single plugin process.
+//
+// If the plugin does not support `--plugin-serve`, as negotiated via `--plugin-protocol`,
+// the Client will fall back to invoking the plugin once per call. If the plugin process
+// exits, it will be relaunched on the next call.
+//
+// Requests sent on a RequestStream are buffered until the RequestStream is closed
+// when using a persistent process. Responses on a ResponseStream are still received
+// as they arrive.
+//
+// The Client must be closed with Client.Close to terminate the plugin process.
+//
+// The default is to invoke the plugin once per call.
+func ClientWithPersistentProcess() ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.persistentProcess = true
+	}
+}
+
+// ClientWithInterceptors will result in the given UnaryInterceptors wrapping every unary call.
+//
+// The first UnaryInterceptor is the outermost, that is, it is called first, and sees the
+// final response and error. If an UnaryInterceptor returns a response other than the
+// response given to Client.Call, the given response is populated with the returned response.
+// If an UnaryInterceptor returns a response alongside an error, HasPartialResponse will
+// return true for the error returned from Client.Call.
+//
+// UnaryInterceptors do not apply to streaming calls.
+func ClientWithInterceptors(interceptors ...UnaryInterceptor) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.interceptors = append(clientOptions.interceptors, interceptors...)
+	}
+}
+
+// ClientWithRetryPolicy will result in unary calls to idempotent Procedures being retried
+// according to the given RetryPolicy.
+//
+// Plugins report which Procedures are idempotent via the spec header, which plugins that
+// only support older protocol versions do not send. Calls to these plugins are never retried,
+// and errors that would otherwise have been retried say so.
+//
+// Retries happen within any UnaryInterceptors, so UnaryInterceptors see a single call.
+//
+// The default is to not retry calls.
+func ClientWithRetryPolicy(retryPolicy RetryPolicy) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.retryPolicy = &retryPolicy
+	}
+}
+
+// ClientWithValidator will result in requests being validated with the given Validator
+// before they are sent, and responses being validated once they are received.
+//
+// Invalid requests result in an *Error with CodeInvalidArgument without the plugin being
+// called, and invalid responses result in an *Error with CodeInternal. For unary calls,
+// validation happens within any UnaryInterceptors.
+//
+// The default is to not validate requests and responses.
+func ClientWithValidator(validator Validator) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.validator = validator
+	}
+}
+
+// ClientWithMinPluginVersion will result in the Client requiring the plugin to have at least
+// the given version, as specified by SpecWithVersion.
+//
+// The version must be a semantic version as per https://semver.org, optionally prefixed
+// with a "v". If the plugin has a lower version or does not specify a version, calls return
+// an *Error with CodeFailedPrecondition.
+//
+// The default is to accept any version.
+func ClientWithMinPluginVersion(minPluginVersion string) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.minPluginVersion = minPluginVersion
+	}
+}
+
 // CallOption is an option for an individual client call.
 type CallOption func(*callOptions)
 
+// CallWithStderr will result in the stderr of the plugin being propagated to the given writer
+// for this call only.
+//
+// This allows the stderr of a plugin to be attributed to a specific call. This takes
+// precedence over ClientWithStderr.
+func CallWithStderr(stderr io.Writer) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.stderr = stderr
+	}
+}
+
+// CallWithEnv will result in the given environment variables, in the form "key=value", being
+// set for the plugin for this call only.
+//
+// The environment variables are given to the Runner via Env.Environ.
+func CallWithEnv(env ...string) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.env = append(callOptions.env, env...)
+	}
+}
+
+// CallWithArgs will result in the given args being passed to the plugin after the args of
+// the Procedure for this call only.
+//
+// Plugins built with this library only accept args after the args of a Procedure that are
+// flags setting fields of the request, such as `--message=foo`, in which case the flags take
+// precedence over the request. Each arg must therefore be a flag of the form `--name=value`
+// or `--name`, and must not be a flag reserved for pluginrpc such as `--plugin-output`.
+// Otherwise, the call fails with CodeInvalidArgument before the plugin is invoked.
+func CallWithArgs(args ...string) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.args = append(callOptions.args, args...)
+	}
+}
+
+// CallWithDir will result in the plugin being run in the given working directory for this
+// call only.
+//
+// The directory is given to the Runner via Env.Dir. As the working directory is a property of
+// the plugin process, calls with a directory always invoke the plugin as a new process, even
+// if ClientWithPersistentProcess is used.
+func CallWithDir(dir string) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.dir = dir
+	}
+}
+
+// CallWithExtraFiles will result in the given open files being given to the plugin as
+// additional file descriptors for this call only, for example to use as side channels.
+//
+// The files are given to the Runner via Env.ExtraFiles, where entry i becomes file descriptor
+// 3+i. As file descriptors are a property of the plugin process, calls with extra files always
+// invoke the plugin as a new process, even if ClientWithPersistentProcess is used.
+//
+// Extra files are not supported on Windows.
+func CallWithExtraFiles(extraFiles ...*os.File) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.extraFiles = append(callOptions.extraFiles, extraFiles...)
+	}
+}
+
+// CallWithTimeout will result in the call being cancelled if it does not complete within
+// the given timeout.
+//
+// If the timeout is exceeded, the call will return an *Error with CodeDeadlineExceeded.
+// For streaming calls, the timeout covers the entire lifetime of the stream.
+func CallWithTimeout(timeout time.Duration) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.timeout = timeout
+	}
+}
+
+// CallWithResponseHeader will result in the Header sent by the plugin being captured
+// into the given Header.
+//
+// For unary calls, the Header is populated once Call returns. For server-streaming calls,
+// the Header is populated once the first response has been received. For client-streaming
+// calls, the Header is populated once CloseAndReceive returns.
+//
+// If the plugin does not send a Header, the Header is left unchanged.
+func CallWithResponseHeader(header *Header) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.responseHeader = header
+	}
+}
+
 // *** PRIVATE ***
 
 type client struct {
-	runner     Runner
-	stderr     io.Writer
-	flagPrefix string
+	runner            Runner
+	stderr            io.Writer
+	flagPrefix        string
+	persistentProcess bool
+	interceptors      []UnaryInterceptor
+	logHandler        slog.Handler
+	retryPolicy       *RetryPolicy
+	validator         Validator
+	minPluginVersion  string
 
-	spec    Spec
-	specErr error
-	lock    sync.RWMutex
+	pluginInfo *pluginInfo
+	lock       sync.RWMutex
+
+	sessionRunner *sessionRunner
+	closed        bool
+	sessionLock   sync.Mutex
 }
 
 func newClient(
@@ -105,94 +353,489 @@ func newClient(
 		option(clientOptions)
 	}
 	return &client{
-		runner:     runner,
-		stderr:     clientOptions.stderr,
-		flagPrefix: clientOptions.flagPrefix,
+		runner:            runner,
+		stderr:            clientOptions.stderr,
+		flagPrefix:        clientOptions.flagPrefix,
+		persistentProcess: clientOptions.persistentProcess,
+		interceptors:      clientOptions.interceptors,
+		logHandler:        clientOptions.logHandler,
+		retryPolicy:       clientOptions.retryPolicy,
+		validator:         clientOptions.validator,
+		minPluginVersion:  clientOptions.minPluginVersion,
 	}
 }
 
+func (c *client) Spec(ctx context.Context) (Spec, error) {
+	pluginInfo, err := c.getPluginInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pluginInfo.spec, nil
+}
+
+func (c *client) FileDescriptorSet(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+	pluginInfo, err := c.getPluginInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pluginInfo.protocolVersion < protocolVersionReflection {
+		return nil, NewErrorf(CodeUnimplemented, "plugin does not support reflection")
+	}
+	runner, err := c.getRunner(pluginInfo)
+	if err != nil {
+		return nil, err
+	}
+	stdout := bytes.NewBuffer(nil)
+	flag := fullFlag(c.flagPrefix, flagReflectSuffix)
+	if err := runner.Run(
+		ctx,
+		Env{
+			Args:   []string{flag},
+			Stdout: stdout,
+			Stderr: c.stderr,
+		},
+	); err != nil {
+		return nil, wrapRunError(ctx, err)
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := unmarshalFlag(stdout.Bytes(), fileDescriptorSet); err != nil {
+		return nil, fmt.Errorf("%s did not return a properly-formed FileDescriptorSet: %w", flag, err)
+	}
+	return fileDescriptorSet, nil
+}
+
 func (c *client) Call(
 	ctx context.Context,
 	procedurePath string,
 	request any,
 	response any,
-	_ ...CallOption,
+	options ...CallOption,
+) error {
+	callOptions := newCallOptions(c.stderr, options...)
+	ctx, cancel := callOptions.withTimeout(ctx)
+	defer cancel()
+	unaryFunc := chainUnaryInterceptors(
+		func(ctx context.Context, procedurePath string, request any) (any, error) {
+			if err := validateMessage(c.validator, request, CodeInvalidArgument); err != nil {
+				return nil, err
+			}
+			if err := c.validateCallArgs(callOptions.args); err != nil {
+				return nil, err
+			}
+			err := c.callWithRetry(ctx, procedurePath, request, response, callOptions)
+			if err == nil {
+				err = validateMessage(c.validator, response, CodeInternal)
+			}
+			if err != nil {
+				if HasPartialResponse(err) {
+					return response, err
+				}
+				return nil, err
+			}
+			return response, nil
+		},
+		c.interceptors,
+	)
+	interceptedResponse, err := unaryFunc(ctx, procedurePath, request)
+	if isNilResponse(interceptedResponse) {
+		return err
+	}
+	if interceptedResponse != response {
+		// An interceptor returned its own response, so populate the given response with it.
+		if copyErr := copyProtoMessage(response, interceptedResponse); copyErr != nil {
+			return copyErr
+		}
+	}
+	if err != nil && !HasPartialResponse(err) {
+		// An interceptor returned a response alongside the error.
+		return newPartialResponseError(err)
+	}
+	return err
+}
+
+func (c *client) CallServerStream(
+	ctx context.Context,
+	procedurePath string,
+	request any,
+	options ...CallOption,
+) (ResponseStream, error) {
+	if err := validateMessage(c.validator, request, CodeInvalidArgument); err != nil {
+		return nil, err
+	}
+	callOptions := newCallOptions(c.stderr, options...)
+	if err := c.validateCallArgs(callOptions.args); err != nil {
+		return nil, err
+	}
+	ctx, cancel := callOptions.withTimeout(ctx)
+	pluginInfo, err := c.getPluginInfo(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	args, err := pluginInfo.getProcedureArgs(procedurePath, callOptions.args)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	data, err := marshalRequest(pluginInfo.format, request, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stdinData, err := pluginInfo.encodeRequestFrames(c.getRequestHeader(ctx), data)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	runner, err := c.getCallRunner(pluginInfo, callOptions)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	stderr, flushStderr := c.getStderr(ctx, procedurePath, callOptions)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := runner.Run(
+			ctx,
+			Env{
+				Args:       args,
+				Environ:    callOptions.env,
+				Dir:        callOptions.dir,
+				ExtraFiles: callOptions.extraFiles,
+				Stdin:      bytes.NewReader(stdinData),
+				Stdout:     pipeWriter,
+				Stderr:     stderr,
+			},
+		)
+		flushStderr()
+		// If err is nil, the reader will receive io.EOF once all frames have been read.
+		// Otherwise, the reader will receive the error from the plugin invocation.
+		_ = pipeWriter.CloseWithError(wrapRunError(ctx, err))
+	}()
+	return newResponseStream(
+		pipeReader,
+		cancel,
+		done,
+		func(header Header) error {
+			return c.handleResponseHeader(pluginInfo, header, callOptions)
+		},
+		c.validator,
+	), nil
+}
+
+func (c *client) CallClientStream(
+	ctx context.Context,
+	procedurePath string,
+	options ...CallOption,
+) (RequestStream, error) {
+	callOptions := newCallOptions(c.stderr, options...)
+	if err := c.validateCallArgs(callOptions.args); err != nil {
+		return nil, err
+	}
+	ctx, cancel := callOptions.withTimeout(ctx)
+	pluginInfo, err := c.getPluginInfo(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	args, err := pluginInfo.getProcedureArgs(procedurePath, callOptions.args)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	headerData, err := pluginInfo.encodeRequestFrames(c.getRequestHeader(ctx), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	runner, err := c.getCallRunner(pluginInfo, callOptions)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	stdout := bytes.NewBuffer(nil)
+	stderr, flushStderr := c.getStderr(ctx, procedurePath, callOptions)
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+		err := runner.Run(
+			ctx,
+			Env{
+				Args:       args,
+				Environ:    callOptions.env,
+				Dir:        callOptions.dir,
+				ExtraFiles: callOptions.extraFiles,
+				Stdin:      pipeReader,
+				Stdout:     stdout,
+				Stderr:     stderr,
+			},
+		)
+		flushStderr()
+		// The plugin has exited, so any further sends will result in io.EOF.
+		_ = pipeReader.CloseWithError(io.EOF)
+		done <- wrapCallRunError(ctx, err, stdout.Bytes())
+	}()
+	return newRequestStream(
+		pipeWriter,
+		pluginInfo.format,
+		headerData,
+		stdout,
+		cancel,
+		done,
+		func(header Header) error {
+			return c.handleResponseHeader(pluginInfo, header, callOptions)
+		},
+		c.validator,
+	), nil
+}
+
+func (c *client) InvalidateSpec() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.invalidatePluginInfo()
+}
+
+func (c *client) Close() error {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	c.closed = true
+	if c.sessionRunner == nil {
+		return nil
+	}
+	err := c.sessionRunner.Close()
+	c.sessionRunner = nil
+	return err
+}
+
+// callWithRetry calls the given Procedure, retrying according to the RetryPolicy of the
+// Client if the Procedure is idempotent.
+func (c *client) callWithRetry(
+	ctx context.Context,
+	procedurePath string,
+	request any,
+	response any,
+	callOptions *callOptions,
 ) error {
-	spec, err := c.getSpec(ctx)
+	for attempt := 1; ; attempt++ {
+		err := c.call(ctx, procedurePath, request, response, callOptions)
+		if errors.Is(err, errSpecIDMismatch) {
+			// The plugin changed since we retrieved the Spec, and the Spec has been
+			// invalidated. Retry once with the new Spec.
+			err = c.call(ctx, procedurePath, request, response, callOptions)
+		}
+		if err == nil {
+			return nil
+		}
+		retry, err := c.shouldRetry(ctx, procedurePath, attempt, err)
+		if !retry {
+			return err
+		}
+		if !c.retryPolicy.waitForRetry(ctx, attempt) {
+			return err
+		}
+	}
+}
+
+// shouldRetry returns true if the attempt with the given number of a call to the given
+// Procedure should be retried after failing with the given error, along with the error to
+// return if not.
+//
+// Plugins only report which Procedures are idempotent if they support spec IDs. If the attempt
+// would otherwise be retried, but the plugin cannot report this, the returned error says so, so
+// that calls are not silently left without retries.
+func (c *client) shouldRetry(ctx context.Context, procedurePath string, attempt int, err error) (bool, error) {
+	if c.retryPolicy == nil || !c.retryPolicy.shouldRetry(attempt, err) {
+		return false, err
+	}
+	pluginInfo, pluginInfoErr := c.getPluginInfo(ctx)
+	if pluginInfoErr != nil {
+		return false, err
+	}
+	if pluginInfo.protocolVersion < protocolVersionSpecID {
+		return false, fmt.Errorf(
+			"%w (not retried, as the plugin only supports protocol version %d, and cannot report which procedures are idempotent)",
+			err,
+			pluginInfo.protocolVersion,
+		)
+	}
+	procedure := pluginInfo.spec.ProcedureForPath(procedurePath)
+	return procedure != nil && procedure.Idempotent(), err
+}
+
+func (c *client) call(
+	ctx context.Context,
+	procedurePath string,
+	request any,
+	response any,
+	callOptions *callOptions,
+) error {
+	pluginInfo, err := c.getPluginInfo(ctx)
 	if err != nil {
 		return err
 	}
-	procedure := spec.ProcedureForPath(procedurePath)
-	if procedure == nil {
-		return fmt.Errorf("no procedure for path %q", procedurePath)
+	args, err := pluginInfo.getProcedureArgs(procedurePath, callOptions.args)
+	if err != nil {
+		return err
 	}
-	data, err := marshalRequest(request)
+	data, err := marshalRequest(pluginInfo.format, request, callOptions.typeResolver)
 	if err != nil {
 		return err
 	}
-	stdin := bytes.NewReader(data)
-	stdout := bytes.NewBuffer(nil)
-	args := procedure.Args()
-	if len(args) == 0 {
-		args = []string{procedure.Path()}
+	stdinData, err := pluginInfo.encodeRequestFrames(c.getRequestHeader(ctx), data)
+	if err != nil {
+		return err
 	}
-	if err := c.runner.Run(
+	runner, err := c.getCallRunner(pluginInfo, callOptions)
+	if err != nil {
+		return err
+	}
+	stdin := bytes.NewReader(stdinData)
+	stdout := bytes.NewBuffer(nil)
+	stderr, flushStderr := c.getStderr(ctx, procedurePath, callOptions)
+	err = runner.Run(
 		ctx,
 		Env{
-			Args:   args,
-			Stdin:  stdin,
-			Stdout: stdout,
-			Stderr: c.stderr,
+			Args:       args,
+			Environ:    callOptions.env,
+			Dir:        callOptions.dir,
+			ExtraFiles: callOptions.extraFiles,
+			Stdin:      stdin,
+			Stdout:     stdout,
+			Stderr:     stderr,
 		},
-	); err != nil {
-		return WrapExitError(err)
+	)
+	flushStderr()
+	if err != nil {
+		return wrapCallRunError(ctx, err, stdout.Bytes())
+	}
+	header, data, err := decodeHeaderFrame(stdout.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := c.handleResponseHeader(pluginInfo, header, callOptions); err != nil {
+		return err
+	}
+	return unmarshalResponseFrame(data, response, callOptions.typeResolver)
+}
+
+// getRequestHeader returns the Header to send with a call.
+//
+// If the context has a deadline, the remaining time is sent, so that the plugin can apply
+// the same deadline.
+func (c *client) getRequestHeader(ctx context.Context) Header {
+	requestHeader := make(Header)
+	if c.logHandler != nil {
+		requestHeader.Set(headerKeyLogFormat, logFormatJSON)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		requestHeader.Set(headerKeyTimeout, encodeTimeout(time.Until(deadline)))
+	}
+	return requestHeader
+}
+
+// getStderr returns the stderr for a call to the Procedure with the given path.
+//
+// If the Client has a slog.Handler, log records written to the returned stderr are forwarded
+// to the slog.Handler. The returned function must be called once the plugin has exited.
+func (c *client) getStderr(ctx context.Context, procedurePath string, callOptions *callOptions) (io.Writer, func()) {
+	if c.logHandler == nil {
+		return callOptions.stderr, func() {}
+	}
+	logWriter := newLogWriter(ctx, c.logHandler, procedurePath, callOptions.stderr)
+	return logWriter, logWriter.Flush
+}
+
+// handleResponseHeader handles the Header sent by the plugin for a call.
+//
+// If the plugin reported that its spec ID does not match the spec ID sent with the call,
+// the cached pluginInfo is invalidated, and an error wrapping errSpecIDMismatch is returned.
+// Otherwise, the Header is captured, if requested.
+func (c *client) handleResponseHeader(pluginInfo *pluginInfo, header Header, callOptions *callOptions) error {
+	if specID := header.Get(headerKeySpecID); specID != "" && specID != pluginInfo.specID {
+		c.lock.Lock()
+		// Only invalidate if another call has not already done so.
+		if c.pluginInfo == pluginInfo {
+			c.invalidatePluginInfo()
+		}
+		c.lock.Unlock()
+		return NewError(CodeFailedPrecondition, errSpecIDMismatch)
 	}
-	return unmarshalResponse(stdout.Bytes(), response)
+	callOptions.setResponseHeader(header)
+	return nil
 }
 
-// TODO: Provide ability for Spec to be invalidated via cache invalidate.
+// getPluginInfo returns the pluginInfo for the plugin, retrieving it if it is not cached.
 //
-// One way this could look: A request sends over a "spec ID", which is an ID that is returned when
-// getting a spec from a plugin. If the plugin does not currently match this spec ID, an error
-// is returned on the response, and the client invalidates the Spec cache, and retries. This will
-// be desirable for situations where clients are long-lived, for example in services.
-func (c *client) getSpec(ctx context.Context) (Spec, error) {
+// Errors are not cached, so that transient errors can be recovered from on the next call.
+func (c *client) getPluginInfo(ctx context.Context) (*pluginInfo, error) {
 	// Difficult to use sync.OnceValues since we want to use the context for cancellation
 	// when passing to the runner. It's awkward if the client constructor took a conteext.
 	c.lock.RLock()
-	if c.spec != nil || c.specErr != nil {
+	if c.pluginInfo != nil {
 		c.lock.RUnlock()
-		return c.spec, c.specErr
+		return c.pluginInfo, nil
 	}
 	c.lock.RUnlock()
 
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	if c.spec != nil || c.specErr != nil {
-		return c.spec, c.specErr
+	if c.pluginInfo != nil {
+		return c.pluginInfo, nil
+	}
+	pluginInfo, err := c.getPluginInfoUncached(ctx)
+	if err != nil {
+		return nil, err
 	}
-	c.spec, c.specErr = c.getSpecUncached(ctx)
-	return c.spec, c.specErr
+	c.pluginInfo = pluginInfo
+	return pluginInfo, nil
 }
 
-func (c *client) getSpecUncached(ctx context.Context) (Spec, error) {
-	if err := c.checkProtocolVersion(ctx); err != nil {
+func (c *client) getPluginInfoUncached(ctx context.Context) (*pluginInfo, error) {
+	protocolVersion, err := c.getProtocolVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pluginInfo := &pluginInfo{
+		protocolVersion: protocolVersion,
+		format:          formatJSON,
+	}
+	if protocolVersion >= protocolVersionBinary {
+		pluginInfo.format = formatBinary
+	}
+	runner, err := c.getRunner(pluginInfo)
+	if err != nil {
 		return nil, err
 	}
+	var stdin io.Reader
+	if protocolVersion >= protocolVersionSpecID {
+		// Sending a header frame results in the plugin sending its spec ID in a header frame.
+		headerFrame, err := encodeHeaderFrame(nil)
+		if err != nil {
+			return nil, err
+		}
+		stdin = bytes.NewReader(headerFrame)
+	}
 	stdout := bytes.NewBuffer(nil)
 	flag := fullFlag(c.flagPrefix, flagSpecSuffix)
-	if err := c.runner.Run(
+	if err := runner.Run(
 		ctx,
 		Env{
 			Args:   []string{flag},
+			Stdin:  stdin,
 			Stdout: stdout,
 			Stderr: c.stderr,
 		},
 	); err != nil {
 		return nil, err
 	}
-	data := stdout.Bytes()
+	header, data, err := decodeHeaderFrame(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s did not return a properly-formed header: %w", flag, err)
+	}
 	if len(data) == 0 {
 		return nil, fmt.Errorf("%s did not return a spec", flag)
 	}
@@ -200,28 +843,116 @@ func (c *client) getSpecUncached(ctx context.Context) (Spec, error) {
 	if err := unmarshalFlag(data, protoSpec); err != nil {
 		return nil, fmt.Errorf("%s did not return a properly-formed spec: %w", flag, err)
 	}
-	return NewSpecForProto(protoSpec)
+	spec, err := newSpecForProto(protoSpec, header)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkPluginVersion(spec); err != nil {
+		return nil, err
+	}
+	pluginInfo.spec = spec
+	pluginInfo.specID = header.Get(headerKeySpecID)
+	pluginInfo.specIDFlag = fullFlag(c.flagPrefix, flagSpecIDSuffix)
+	return pluginInfo, nil
 }
 
-func (c *client) checkProtocolVersion(ctx context.Context) error {
-	version, err := c.getProtocolVersionUncached(ctx)
+// checkPluginVersion checks that the version of the plugin is at least the minimum version
+// required by the Client, if any.
+func (c *client) checkPluginVersion(spec Spec) error {
+	if c.minPluginVersion == "" {
+		return nil
+	}
+	minVersion, err := parseSemver(c.minPluginVersion)
+	if err != nil {
+		return fmt.Errorf("invalid minimum plugin version: %w", err)
+	}
+	if spec.Version() == "" {
+		return NewErrorf(CodeFailedPrecondition, "plugin does not specify a version, but version %s is required", c.minPluginVersion)
+	}
+	version, err := parseSemver(spec.Version())
 	if err != nil {
 		return err
 	}
-	if version != protocolVersion {
-		flag := c.getProtocolFullFlag()
-		return fmt.Errorf("%s returned unknown protocol version %d", flag, version)
+	if version.compare(minVersion) < 0 {
+		return NewErrorf(CodeFailedPrecondition, "plugin version %s is less than the required version %s", spec.Version(), c.minPluginVersion)
 	}
 	return nil
 }
 
+// invalidatePluginInfo invalidates the cached pluginInfo.
+//
+// If a persistent plugin process is running, it is closed in the background once all
+// in-flight calls have completed, so that the next call launches the current plugin.
+//
+// This must be called with c.lock held.
+func (c *client) invalidatePluginInfo() {
+	c.pluginInfo = nil
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if sessionRunner := c.sessionRunner; sessionRunner != nil {
+		c.sessionRunner = nil
+		go func() { _ = sessionRunner.Close() }()
+	}
+}
+
+// getRunner returns the Runner to use for calls to the plugin described by the pluginInfo.
+//
+// If a persistent process was requested and the plugin supports it, this returns a Runner
+// that multiplexes calls over the persistent process, launching the process if necessary.
+func (c *client) getRunner(pluginInfo *pluginInfo) (Runner, error) {
+	if !c.persistentProcess || pluginInfo.protocolVersion < protocolVersionServe {
+		return c.runner, nil
+	}
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.closed {
+		return nil, errors.New("client closed")
+	}
+	if c.sessionRunner == nil || c.sessionRunner.Closed() {
+		c.sessionRunner = newSessionRunner(
+			c.runner,
+			[]string{fullFlag(c.flagPrefix, flagServeSuffix)},
+			c.stderr,
+		)
+	}
+	return c.sessionRunner, nil
+}
+
+// getCallRunner returns the Runner to use for a call.
+//
+// The working directory and extra files are properties of the plugin process, so calls
+// with these cannot be multiplexed over a persistent process, and always use the Runner
+// directly.
+func (c *client) getCallRunner(pluginInfo *pluginInfo, callOptions *callOptions) (Runner, error) {
+	if callOptions.dir != "" || len(callOptions.extraFiles) > 0 {
+		return c.runner, nil
+	}
+	return c.getRunner(pluginInfo)
+}
+
+func (c *client) getProtocolVersion(ctx context.Context) (int, error) {
+	version, err := c.getProtocolVersionUncached(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if version < protocolVersion || version > maxProtocolVersion {
+		flag := c.getProtocolFullFlag()
+		return 0, fmt.Errorf("%s returned unknown protocol version %d", flag, version)
+	}
+	return version, nil
+}
+
 func (c *client) getProtocolVersionUncached(ctx context.Context) (int, error) {
 	stdout := bytes.NewBuffer(nil)
 	flag := c.getProtocolFullFlag()
 	if err := c.runner.Run(
 		ctx,
 		Env{
-			Args:   []string{flag},
+			Args: []string{flag},
+			// We send the maximum protocol version we support, and the plugin responds with
+			// the protocol version to use. Plugins that only support the base protocol version
+			// will ignore stdin.
+			Stdin:  strings.NewReader(strconv.Itoa(maxProtocolVersion) + "\n"),
 			Stdout: stdout,
 			Stderr: c.stderr,
 		},
@@ -243,9 +974,88 @@ func (c *client) getProtocolFullFlag() string {
 	return fullFlag(c.flagPrefix, flagProtocolSuffix)
 }
 
+// validateCallArgs validates the args given with CallWithArgs.
+//
+// The only args that plugins built with this library accept after the args of a Procedure
+// are flags that set fields of the request, so any other arg would only fail once the plugin
+// is invoked. Flags reserved for pluginrpc, such as `--plugin-output`, are rejected as well,
+// as they would change the output that the client reads.
+func (c *client) validateCallArgs(args []string) error {
+	reservedFlagPrefix := fullFlag(c.flagPrefix, "plugin-")
+	for _, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		if !strings.HasPrefix(name, "--") || len(name) == len("--") || strings.HasPrefix(name, "---") {
+			return NewErrorf(CodeInvalidArgument, "arg %q given with CallWithArgs is not a flag of the form --name=value", arg)
+		}
+		if strings.HasPrefix(name, reservedFlagPrefix) {
+			return NewErrorf(CodeInvalidArgument, "flag %q given with CallWithArgs is reserved for pluginrpc", name)
+		}
+	}
+	return nil
+}
+
+// pluginInfo is the information about a plugin retrieved via `--plugin-protocol`
+// and `--plugin-spec`.
+//
+// pluginInfos are immutable, so that calls can use a consistent view of the plugin
+// even if the pluginInfo is invalidated concurrently.
+type pluginInfo struct {
+	protocolVersion int
+	format          format
+	spec            Spec
+	// specID is the ID of the spec, or empty if the plugin does not support spec IDs.
+	specID     string
+	specIDFlag string
+}
+
+// getProcedureArgs returns the args to invoke the Procedure with the given path, followed
+// by the given extra args.
+//
+// If the plugin supports spec IDs, the args are preceded by the spec ID flag, so that the
+// plugin can verify that its Spec has not changed.
+func (p *pluginInfo) getProcedureArgs(procedurePath string, extraArgs []string) ([]string, error) {
+	procedure := p.spec.ProcedureForPath(procedurePath)
+	if procedure == nil {
+		return nil, fmt.Errorf("no procedure for path %q", procedurePath)
+	}
+	args := procedure.Args()
+	if len(args) == 0 {
+		args = []string{procedure.Path()}
+	}
+	if p.specID != "" {
+		args = append([]string{p.specIDFlag, p.specID}, args...)
+	}
+	return append(args, extraArgs...), nil
+}
+
+// encodeRequestFrames encodes the request data as a frame, preceded by a header frame
+// with the request Header if the plugin supports headers.
+//
+// If data is nil, only the header frame is encoded, if any.
+func (p *pluginInfo) encodeRequestFrames(requestHeader Header, data []byte) ([]byte, error) {
+	var frames []byte
+	if p.protocolVersion >= protocolVersionHeader {
+		headerFrame, err := encodeHeaderFrame(requestHeader)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, headerFrame...)
+	}
+	if data != nil {
+		frames = append(frames, encodeFrame(p.format, data)...)
+	}
+	return frames, nil
+}
+
 type clientOptions struct {
-	stderr     io.Writer
-	flagPrefix string
+	stderr            io.Writer
+	flagPrefix        string
+	persistentProcess bool
+	interceptors      []UnaryInterceptor
+	logHandler        slog.Handler
+	retryPolicy       *RetryPolicy
+	validator         Validator
+	minPluginVersion  string
 }
 
 func newClientOptions() *clientOptions {
@@ -254,4 +1064,77 @@ func newClientOptions() *clientOptions {
 	}
 }
 
-type callOptions struct{}
+type callOptions struct {
+	stderr         io.Writer
+	env            []string
+	args           []string
+	dir            string
+	extraFiles     []*os.File
+	timeout        time.Duration
+	responseHeader *Header
+	// typeResolver is used by DynamicClients, as the types of their requests and responses
+	// are usually not in protoregistry.GlobalTypes.
+	typeResolver typeResolver
+}
+
+// wrapCallRunError wraps an error from running a plugin for a unary or client-streaming
+// call, given the data the plugin wrote to stdout.
+//
+// If the plugin wrote an *Error before exiting with a non-zero exit code, for example
+// because a handler panicked, and the context is not done, this returns the *Error.
+// Otherwise, this returns the result of wrapRunError.
+func wrapCallRunError(ctx context.Context, err error, stdoutData []byte) error {
+	if err != nil && ctx.Err() == nil {
+		if exitErr := unmarshalExitError(stdoutData); exitErr != nil {
+			return exitErr
+		}
+	}
+	return wrapRunError(ctx, err)
+}
+
+// wrapRunError wraps an error from running a plugin.
+//
+// If the context is done, this returns an *Error with CodeCanceled or CodeDeadlineExceeded.
+// Otherwise, this returns an *ExitError.
+func wrapRunError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return NewError(codeForContextError(ctxErr), ctxErr)
+	}
+	return WrapExitError(err)
+}
+
+// callWithTypeResolver results in the types of google.protobuf.Any messages being resolved
+// with the given typeResolver when the JSON format is used.
+func callWithTypeResolver(typeResolver typeResolver) CallOption {
+	return func(callOptions *callOptions) {
+		callOptions.typeResolver = typeResolver
+	}
+}
+
+func newCallOptions(defaultStderr io.Writer, options ...CallOption) *callOptions {
+	callOptions := &callOptions{
+		stderr: defaultStderr,
+	}
+	for _, option := range options {
+		option(callOptions)
+	}
+	return callOptions
+}
+
+// withTimeout returns a context with the timeout applied, if any.
+func (c *callOptions) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// setResponseHeader populates the captured response Header, if requested.
+func (c *callOptions) setResponseHeader(header Header) {
+	if c.responseHeader != nil && header != nil {
+		*c.responseHeader = header
+	}
+}
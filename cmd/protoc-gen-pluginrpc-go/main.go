@@ -20,6 +20,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -83,8 +84,8 @@ func generate(plugin *protogen.Plugin, file *protogen.File) error {
 
 	for _, service := range file.Services {
 		for _, method := range service.Methods {
-			if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
-				return fmt.Errorf("streaming methods not supported: %s/%s", service.Desc.Name(), method.Desc.Name())
+			if method.Desc.IsStreamingClient() && method.Desc.IsStreamingServer() {
+				return fmt.Errorf("bidirectional streaming methods not supported: %s/%s", service.Desc.Name(), method.Desc.Name())
 			}
 		}
 	}
@@ -108,6 +109,7 @@ func generate(plugin *protogen.Plugin, file *protogen.File) error {
 
 	generatePreamble(generatedFile, file)
 	generatePathConstants(generatedFile, file.Services)
+	generateDescriptors(generatedFile, file)
 	for _, service := range file.Services {
 		names := newNames(service)
 		generateSpecBuilder(generatedFile, service, names)
@@ -185,6 +187,22 @@ func generatePathConstants(g *protogen.GeneratedFile, services []*protogen.Servi
 	g.P()
 }
 
+func generateDescriptors(g *protogen.GeneratedFile, file *protogen.File) {
+	g.P("var (")
+	for _, service := range file.Services {
+		serviceDescriptorName := serviceDescriptorVarName(service)
+		g.P(serviceDescriptorName, " = ",
+			g.QualifiedGoIdent(file.GoDescriptorIdent),
+			".Services().ByName(", strconv.Quote(string(service.Desc.Name())), ")")
+		for _, method := range service.Methods {
+			g.P(methodDescriptorVarName(method), " = ",
+				serviceDescriptorName, ".Methods().ByName(", strconv.Quote(string(method.Desc.Name())), ")")
+		}
+	}
+	g.P(")")
+	g.P()
+}
+
 func generateSpecBuilder(g *protogen.GeneratedFile, service *protogen.Service, names names) {
 	wrapComments(g, names.SpecBuilder, " builds a Spec for the ", service.Desc.FullName(), " service.")
 	if isDeprecatedService(service) {
@@ -199,20 +217,57 @@ func generateSpecBuilder(g *protogen.GeneratedFile, service *protogen.Service, n
 	g.P("}")
 	g.P()
 	wrapComments(g, "Build builds a Spec for the ", service.Desc.FullName(), " service.")
-	g.P("func (s ", names.SpecBuilder, ") Build() (", pluginrpcPackage.Ident("Spec"), ", error) {")
+	g.P("//")
+	wrapComments(g, "The description of the Spec defaults to the comments of the service.")
+	g.P("func (s ", names.SpecBuilder, ") Build(options ...", pluginrpcPackage.Ident("SpecOption"), ") (", pluginrpcPackage.Ident("Spec"), ", error) {")
 	g.P("procedures := make([]", pluginrpcPackage.Ident("Procedure"), ", 0, ", len(service.Methods), ")")
 	for i, method := range service.Methods {
 		equals := "="
 		if i == 0 {
 			equals = ":="
 		}
-		g.P("procedure, err ", equals, " ", pluginrpcPackage.Ident("NewProcedure"), "(", pathConstName(method), ", s.", method.GoName, "...)")
+		g.P("procedure, err ", equals, " ", pluginrpcPackage.Ident("NewProcedure"), "(")
+		g.P(pathConstName(method), ",")
+		g.P("append(")
+		g.P("[]", pluginrpcPackage.Ident("ProcedureOption"), "{")
+		g.P(pluginrpcPackage.Ident("ProcedureWithMethodDescriptor"), "(", methodDescriptorVarName(method), "),")
+		if description := commentsDescription(method.Comments.Leading); description != "" {
+			g.P(pluginrpcPackage.Ident("ProcedureWithDescription"), "(", strconv.Quote(description), "),")
+		}
+		if isDeprecatedService(service) || isDeprecatedMethod(method) {
+			g.P(pluginrpcPackage.Ident("ProcedureWithDeprecated"), "(),")
+		}
+		if isIdempotentMethod(method) {
+			g.P(pluginrpcPackage.Ident("ProcedureWithIdempotent"), "(),")
+		}
+		if method.Desc.IsStreamingClient() {
+			g.P(pluginrpcPackage.Ident("ProcedureWithStreamType"), "(", pluginrpcPackage.Ident("StreamTypeClient"), "),")
+		}
+		if method.Desc.IsStreamingServer() {
+			g.P(pluginrpcPackage.Ident("ProcedureWithStreamType"), "(", pluginrpcPackage.Ident("StreamTypeServer"), "),")
+		}
+		g.P("},")
+		g.P("s.", method.GoName, "...,")
+		g.P(")...,")
+		g.P(")")
 		g.P("if err != nil {")
 		g.P("return nil, err")
 		g.P("}")
 		g.P("procedures = append(procedures, procedure)")
 	}
-	g.P("return ", pluginrpcPackage.Ident("NewSpec"), "(procedures)")
+	if description := commentsDescription(service.Comments.Leading); description != "" {
+		g.P("return ", pluginrpcPackage.Ident("NewSpec"), "(")
+		g.P("procedures,")
+		g.P("append(")
+		g.P("[]", pluginrpcPackage.Ident("SpecOption"), "{")
+		g.P(pluginrpcPackage.Ident("SpecWithDescription"), "(", strconv.Quote(description), "),")
+		g.P("},")
+		g.P("options...,")
+		g.P(")...,")
+		g.P(")")
+	} else {
+		g.P("return ", pluginrpcPackage.Ident("NewSpec"), "(procedures, options...)")
+	}
 	g.P("}")
 	g.P()
 }
@@ -274,8 +329,31 @@ func generateClientMethod(g *protogen.GeneratedFile, method *protogen.Method, na
 		deprecated(g)
 	}
 	g.P("func (c *", receiver, ") ", clientSignature(g, method, true /* named */), " {")
+	if method.Desc.IsStreamingClient() {
+		g.P("requestStream, err := c.client.CallClientStream(ctx, ", pathConstName(method), ", opts...)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return ", pluginrpcPackage.Ident("NewClientStreamForClient"), "[", method.Input.GoIdent, ", ", method.Output.GoIdent, "](requestStream), nil")
+		g.P("}")
+		g.P()
+		return
+	}
+	if method.Desc.IsStreamingServer() {
+		g.P("responseStream, err := c.client.CallServerStream(ctx, ", pathConstName(method), ", req, opts...)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return ", pluginrpcPackage.Ident("NewServerStreamForClient"), "[", method.Output.GoIdent, "](responseStream), nil")
+		g.P("}")
+		g.P()
+		return
+	}
 	g.P("res := &", g.QualifiedGoIdent(method.Output.GoIdent), "{}")
 	g.P("if err := c.client.Call(ctx, ", pathConstName(method), ", req, res, opts...); err != nil {")
+	g.P("if ", pluginrpcPackage.Ident("HasPartialResponse"), "(err) {")
+	g.P("return res, err")
+	g.P("}")
 	g.P("return nil, err")
 	g.P("}")
 	g.P("return res, nil")
@@ -378,6 +456,37 @@ func generateServerMethod(g *protogen.GeneratedFile, method *protogen.Method, na
 		deprecated(g)
 	}
 	g.P("func (c *", receiver, ") ", serverSignature(g, method, true /* named */), " {")
+	if method.Desc.IsStreamingClient() {
+		g.P("return c.handler.HandleClientStream(")
+		g.P("ctx,")
+		g.P("env,")
+		g.P("func(ctx ", contextPackage.Ident("Context"), ", receive func(any) error) (any, error) {")
+		g.P("return c.", unexport(names.Handler), ".", method.GoName, "(ctx, ",
+			pluginrpcPackage.Ident("NewClientStream"), "[", method.Input.GoIdent, "](receive))")
+		g.P("},")
+		g.P(")")
+		g.P("}")
+		g.P()
+		return
+	}
+	if method.Desc.IsStreamingServer() {
+		g.P("return c.handler.HandleServerStream(")
+		g.P("ctx,")
+		g.P("env,")
+		g.P("&", g.QualifiedGoIdent(method.Input.GoIdent), "{},")
+		g.P("func(ctx ", contextPackage.Ident("Context"), ", anyReq any, send func(any) error) error {")
+		g.P("req, ok := anyReq.(*", g.QualifiedGoIdent(method.Input.GoIdent), ")")
+		g.P("if !ok {")
+		g.P("return ", fmtPackage.Ident("Errorf"), `("could not cast %T to a *`, g.QualifiedGoIdent(method.Input.GoIdent), `", anyReq)`)
+		g.P("}")
+		g.P("return c.", unexport(names.Handler), ".", method.GoName, "(ctx, req, ",
+			pluginrpcPackage.Ident("NewServerStream"), "[", method.Output.GoIdent, "](send))")
+		g.P("},")
+		g.P(")")
+		g.P("}")
+		g.P()
+		return
+	}
 	g.P("return c.handler.Handle(")
 	g.P("ctx,")
 	g.P("env,")
@@ -395,7 +504,7 @@ func generateServerMethod(g *protogen.GeneratedFile, method *protogen.Method, na
 }
 
 func clientSignature(g *protogen.GeneratedFile, method *protogen.Method, named bool) string {
-	// unary; symmetric so we can re-use server templating
+	// symmetric so we can re-use server templating
 	return method.GoName + clientSignatureParams(g, method, named)
 }
 
@@ -406,11 +515,22 @@ func clientSignatureParams(g *protogen.GeneratedFile, method *protogen.Method, n
 	if !named {
 		ctxName, reqName, optsName = "", "", ""
 	}
-	// unary
-	return "(" + ctxName + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
+	if method.Desc.IsStreamingClient() {
+		return "(" + ctxName + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
+			", " + optsName + "..." + g.QualifiedGoIdent(pluginrpcPackage.Ident("CallOption")) + ") " +
+			"(*" + g.QualifiedGoIdent(pluginrpcPackage.Ident("ClientStreamForClient")) +
+			"[" + g.QualifiedGoIdent(method.Input.GoIdent) + ", " + g.QualifiedGoIdent(method.Output.GoIdent) + "]" + ", error)"
+	}
+	params := "(" + ctxName + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
 		", " + reqName + "*" + g.QualifiedGoIdent(method.Input.GoIdent) +
-		", " + optsName + "..." + g.QualifiedGoIdent(pluginrpcPackage.Ident("CallOption")) + ") " +
-		"(*" + g.QualifiedGoIdent(method.Output.GoIdent) + ", error)"
+		", " + optsName + "..." + g.QualifiedGoIdent(pluginrpcPackage.Ident("CallOption")) + ") "
+	if method.Desc.IsStreamingServer() {
+		return params +
+			"(*" + g.QualifiedGoIdent(pluginrpcPackage.Ident("ServerStreamForClient")) +
+			"[" + g.QualifiedGoIdent(method.Output.GoIdent) + "]" + ", error)"
+	}
+	// unary
+	return params + "(*" + g.QualifiedGoIdent(method.Output.GoIdent) + ", error)"
 }
 
 func handlerSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
@@ -420,8 +540,21 @@ func handlerSignature(g *protogen.GeneratedFile, method *protogen.Method) string
 func handlerSignatureParams(g *protogen.GeneratedFile, method *protogen.Method, named bool) string {
 	ctxName := "ctx "
 	reqName := "req "
+	streamName := "stream "
 	if !named {
-		ctxName, reqName = "", ""
+		ctxName, reqName, streamName = "", "", ""
+	}
+	if method.Desc.IsStreamingClient() {
+		return "(" + ctxName + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
+			", " + streamName + "*" + g.QualifiedGoIdent(pluginrpcPackage.Ident("ClientStream")) +
+			"[" + g.QualifiedGoIdent(method.Input.GoIdent) + "]) " +
+			"(*" + g.QualifiedGoIdent(method.Output.GoIdent) + ", error)"
+	}
+	if method.Desc.IsStreamingServer() {
+		return "(" + ctxName + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
+			", " + reqName + "*" + g.QualifiedGoIdent(method.Input.GoIdent) +
+			", " + streamName + "*" + g.QualifiedGoIdent(pluginrpcPackage.Ident("ServerStream")) +
+			"[" + g.QualifiedGoIdent(method.Output.GoIdent) + "]) error"
 	}
 	// unary
 	return "(" + ctxName + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
@@ -448,6 +581,27 @@ func pathConstName(m *protogen.Method) string {
 	return fmt.Sprintf("%s%sPath", m.Parent.GoName, m.GoName)
 }
 
+func serviceDescriptorVarName(service *protogen.Service) string {
+	return unexport(service.GoName) + "ServiceDescriptor"
+}
+
+func methodDescriptorVarName(method *protogen.Method) string {
+	return unexport(method.Parent.GoName) + method.GoName + "MethodDescriptor"
+}
+
+// commentsDescription returns the text of the given comments, with any surrounding blank
+// lines removed.
+//
+// Only the single space that protoc leaves after "//" is removed from each line, so that
+// the indentation of lists and code blocks within the comments is preserved.
+func commentsDescription(comments protogen.Comments) string {
+	lines := strings.Split(string(comments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
 func isDeprecatedService(service *protogen.Service) bool {
 	serviceOptions, ok := service.Desc.Options().(*descriptorpb.ServiceOptions)
 	return ok && serviceOptions.GetDeprecated()
@@ -458,6 +612,21 @@ func isDeprecatedMethod(method *protogen.Method) bool {
 	return ok && methodOptions.GetDeprecated()
 }
 
+// isIdempotentMethod returns true if the idempotency_level of the method is
+// NO_SIDE_EFFECTS or IDEMPOTENT.
+func isIdempotentMethod(method *protogen.Method) bool {
+	methodOptions, ok := method.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok {
+		return false
+	}
+	switch methodOptions.GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_NO_SIDE_EFFECTS, descriptorpb.MethodOptions_IDEMPOTENT:
+		return true
+	default:
+		return false
+	}
+}
+
 // Raggedy comments in the generated code are driving me insane. This
 // word-wrapping function is ruinously inefficient, but it gets the job done.
 func wrapComments(g *protogen.GeneratedFile, elems ...any) {
@@ -15,6 +15,8 @@
 package pluginrpc
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
@@ -163,3 +165,11 @@ func CodeForProto(protoCode pluginrpcv1beta1.Code) Code {
 func isValidCode(code Code) bool {
 	return code >= minCode && code <= maxCode
 }
+
+// codeForContextError returns the Code for an error returned from context.Context.Err.
+func codeForContextError(err error) Code {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CodeDeadlineExceeded
+	}
+	return CodeCanceled
+}
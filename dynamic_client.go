@@ -0,0 +1,285 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// DynamicClient is a client that calls the Procedures of plugins by path, without requiring
+// the generated code for the request and response types.
+//
+// The request and response types of a Procedure are resolved from the method that the
+// Procedure implements, which is named by its path, for example
+// "/buf.pluginrpc.example.v1.EchoService/EchoRequest". By default, the descriptors of
+// the plugin are retrieved via Client.FileDescriptorSet, and cached until the Spec of the
+// plugin changes.
+//
+// DynamicClients only call unary Procedures. Streaming Procedures can be called by creating
+// messages for the types returned by MethodDescriptor with dynamicpb.NewMessage, and passing
+// them to the streaming methods of the Client.
+type DynamicClient interface {
+	// MethodDescriptor returns the descriptor of the method that the Procedure with the
+	// given path implements.
+	//
+	// If the plugin has no Procedure with the given path, or the method cannot be resolved,
+	// this returns an *Error with CodeUnimplemented.
+	MethodDescriptor(ctx context.Context, procedurePath string) (protoreflect.MethodDescriptor, error)
+	// Call calls the given unary Procedure, and returns the response.
+	//
+	// The request must be of the request type of the Procedure, and can be any proto.Message,
+	// including a *dynamicpb.Message. If the plugin returned a response alongside an error,
+	// the response is returned alongside the error, and HasPartialResponse returns true for
+	// the error.
+	Call(
+		ctx context.Context,
+		procedurePath string,
+		request proto.Message,
+		options ...CallOption,
+	) (*dynamicpb.Message, error)
+	// CallJSON calls the given unary Procedure with the JSON-encoded request, and returns
+	// the response.
+	//
+	// The request is unmarshaled with protojson. Otherwise, this behaves the same as Call.
+	CallJSON(
+		ctx context.Context,
+		procedurePath string,
+		requestJSON []byte,
+		options ...CallOption,
+	) (*dynamicpb.Message, error)
+
+	isDynamicClient()
+}
+
+// NewDynamicClient returns a new DynamicClient for the given Client.
+//
+// The DynamicClient does not close the Client. Callers are responsible for closing the Client.
+func NewDynamicClient(client Client, options ...DynamicClientOption) DynamicClient {
+	return newDynamicClient(client, options...)
+}
+
+// DynamicClientOption is an option for a new DynamicClient.
+type DynamicClientOption func(*dynamicClientOptions)
+
+// DynamicClientWithResolver will result in descriptors being resolved with the given resolver,
+// instead of being retrieved from the plugin via Client.FileDescriptorSet.
+//
+// This allows plugins that do not support reflection to be called. For example,
+// protoregistry.GlobalFiles resolves the descriptors of all generated code linked into
+// the binary.
+//
+// Plugins that do not support reflection may only support the JSON format, in which case
+// the types of requests and responses are resolved from the resolver if the resolver is a
+// *protoregistry.Files, and from protoregistry.GlobalTypes otherwise.
+func DynamicClientWithResolver(resolver protodesc.Resolver) DynamicClientOption {
+	return func(dynamicClientOptions *dynamicClientOptions) {
+		dynamicClientOptions.resolver = resolver
+	}
+}
+
+// *** PRIVATE ***
+
+type dynamicClient struct {
+	client   Client
+	resolver protodesc.Resolver
+
+	// spec is the Spec that files were retrieved for.
+	spec  Spec
+	files *protoregistry.Files
+	// types resolves the types of the files, or of the resolver if the resolver is a
+	// *protoregistry.Files.
+	types typeResolver
+	lock  sync.Mutex
+}
+
+func newDynamicClient(client Client, options ...DynamicClientOption) *dynamicClient {
+	dynamicClientOptions := newDynamicClientOptions()
+	for _, option := range options {
+		option(dynamicClientOptions)
+	}
+	dynamicClient := &dynamicClient{
+		client:   client,
+		resolver: dynamicClientOptions.resolver,
+	}
+	if files, ok := dynamicClientOptions.resolver.(*protoregistry.Files); ok {
+		dynamicClient.types = dynamicpb.NewTypes(files)
+	}
+	return dynamicClient
+}
+
+func (d *dynamicClient) MethodDescriptor(ctx context.Context, procedurePath string) (protoreflect.MethodDescriptor, error) {
+	methodDescriptor, _, err := d.getMethodDescriptor(ctx, procedurePath)
+	return methodDescriptor, err
+}
+
+func (d *dynamicClient) Call(
+	ctx context.Context,
+	procedurePath string,
+	request proto.Message,
+	options ...CallOption,
+) (*dynamicpb.Message, error) {
+	methodDescriptor, types, err := d.getUnaryMethodDescriptor(ctx, procedurePath)
+	if err != nil {
+		return nil, err
+	}
+	if requestName := request.ProtoReflect().Descriptor().FullName(); requestName != methodDescriptor.Input().FullName() {
+		return nil, NewErrorf(
+			CodeInvalidArgument,
+			"expected request of type %s for path %q, got %s",
+			methodDescriptor.Input().FullName(),
+			procedurePath,
+			requestName,
+		)
+	}
+	return d.call(ctx, procedurePath, methodDescriptor, types, request, options...)
+}
+
+func (d *dynamicClient) CallJSON(
+	ctx context.Context,
+	procedurePath string,
+	requestJSON []byte,
+	options ...CallOption,
+) (*dynamicpb.Message, error) {
+	methodDescriptor, types, err := d.getUnaryMethodDescriptor(ctx, procedurePath)
+	if err != nil {
+		return nil, err
+	}
+	request := dynamicpb.NewMessage(methodDescriptor.Input())
+	if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal(requestJSON, request); err != nil {
+		return nil, NewError(CodeInvalidArgument, err)
+	}
+	return d.call(ctx, procedurePath, methodDescriptor, types, request, options...)
+}
+
+func (*dynamicClient) isDynamicClient() {}
+
+func (d *dynamicClient) call(
+	ctx context.Context,
+	procedurePath string,
+	methodDescriptor protoreflect.MethodDescriptor,
+	types typeResolver,
+	request proto.Message,
+	options ...CallOption,
+) (*dynamicpb.Message, error) {
+	response := dynamicpb.NewMessage(methodDescriptor.Output())
+	// The given options are applied after, so that they take precedence.
+	options = append([]CallOption{callWithTypeResolver(types)}, options...)
+	if err := d.client.Call(ctx, procedurePath, request, response, options...); err != nil {
+		if HasPartialResponse(err) {
+			return response, err
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// getMethodDescriptor returns the descriptor of the method that the Procedure with the
+// given path implements, along with the typeResolver for the types of the plugin.
+//
+// The typeResolver may be nil. See typeResolver.
+func (d *dynamicClient) getMethodDescriptor(
+	ctx context.Context,
+	procedurePath string,
+) (protoreflect.MethodDescriptor, typeResolver, error) {
+	spec, err := d.client.Spec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if spec.ProcedureForPath(procedurePath) == nil {
+		return nil, nil, NewErrorf(CodeUnimplemented, "no procedure for path %q", procedurePath)
+	}
+	resolver, types, err := d.getResolver(ctx, spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Paths are of the form "/package.Service/Method".
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(procedurePath, "/"), "/")
+	if !ok || !strings.HasPrefix(procedurePath, "/") {
+		return nil, nil, NewErrorf(CodeUnimplemented, "path %q does not name a method", procedurePath)
+	}
+	descriptor, err := resolver.FindDescriptorByName(protoreflect.FullName(serviceName).Append(protoreflect.Name(methodName)))
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return nil, nil, NewErrorf(CodeUnimplemented, "no method descriptor for path %q", procedurePath)
+		}
+		return nil, nil, err
+	}
+	methodDescriptor, ok := descriptor.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, nil, NewErrorf(CodeUnimplemented, "no method descriptor for path %q", procedurePath)
+	}
+	return methodDescriptor, types, nil
+}
+
+func (d *dynamicClient) getUnaryMethodDescriptor(
+	ctx context.Context,
+	procedurePath string,
+) (protoreflect.MethodDescriptor, typeResolver, error) {
+	methodDescriptor, types, err := d.getMethodDescriptor(ctx, procedurePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+		return nil, nil, NewErrorf(CodeInvalidArgument, "procedure for path %q is streaming", procedurePath)
+	}
+	return methodDescriptor, types, nil
+}
+
+// getResolver returns the resolver for the descriptors of the plugin with the given Spec,
+// along with the typeResolver for the types of the plugin.
+//
+// If no resolver was given, the descriptors are retrieved from the plugin, and cached
+// until the Spec of the plugin changes. Errors are not cached.
+func (d *dynamicClient) getResolver(ctx context.Context, spec Spec) (protodesc.Resolver, typeResolver, error) {
+	if d.resolver != nil {
+		return d.resolver, d.types, nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.files != nil && d.spec == spec {
+		return d.files, d.types, nil
+	}
+	fileDescriptorSet, err := d.client.FileDescriptorSet(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := protodesc.NewFiles(fileDescriptorSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	d.spec = spec
+	d.files = files
+	d.types = dynamicpb.NewTypes(files)
+	return files, d.types, nil
+}
+
+type dynamicClientOptions struct {
+	resolver protodesc.Resolver
+}
+
+func newDynamicClientOptions() *dynamicClientOptions {
+	return &dynamicClientOptions{}
+}
@@ -15,25 +15,62 @@
 package pluginrpc
 
 import (
+	"context"
 	"io"
 	"os"
+	"slices"
 )
 
-// OSEnv is an Env using os.Args, os.Stdin, os.Stdout, and os.Stderr.
+// OSEnv is an Env using os.Args, os.Environ(), os.Stdin, os.Stdout, and os.Stderr.
 var OSEnv = Env{
-	Args:   os.Args[1:],
-	Stdin:  os.Stdin,
-	Stdout: os.Stdout,
-	Stderr: os.Stderr,
+	Args:    os.Args[1:],
+	Environ: os.Environ(),
+	Stdin:   os.Stdin,
+	Stdout:  os.Stdout,
+	Stderr:  os.Stderr,
 }
 
 // Env specifies an environment used to invoke a plugin.
 //
-// This abstracts away args, stdin, stdout, and stderr. Envs are used
-// by Runners and Servers.
+// This abstracts away args, environment variables, the working directory, stdin,
+// stdout, stderr, and extra files. Envs are used by Runners and Servers.
 type Env struct {
-	Args   []string
+	Args []string
+	// Environ are environment variables in the form "key=value".
+	//
+	// Runners add these to the environment variables of the plugin. If a key is
+	// repeated, the last value takes precedence.
+	Environ []string
+	// Dir is the working directory to run the plugin in.
+	//
+	// If empty, Runners use their default working directory.
+	Dir    string
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+	// ExtraFiles are additional open files to give to the plugin, for example to use as
+	// side channels. As with exec.Cmd, entry i becomes file descriptor 3+i.
+	//
+	// ExtraFiles are not supported on Windows.
+	ExtraFiles []*os.File
+}
+
+// EnvironFromContext returns the environment variables of the call being handled, in the
+// form "key=value".
+//
+// This is meant to be used within handlers instead of os.Environ, so that environment
+// variables given in an Env are visible to plugins when run with NewServerRunner.
+//
+// If the context is not the context of a call being handled, this returns nil.
+func EnvironFromContext(ctx context.Context) []string {
+	environ, _ := ctx.Value(environContextKey{}).([]string)
+	return slices.Clone(environ)
+}
+
+// *** PRIVATE ***
+
+type environContextKey struct{}
+
+func withEnviron(ctx context.Context, environ []string) context.Context {
+	return context.WithValue(ctx, environContextKey{}, environ)
 }
@@ -15,17 +15,23 @@
 package pluginrpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
+	errorv1 "github.com/bufbuild/pluginrpc-go/internal/gen/buf/pluginrpc/error/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // Error is an error with a Code.
 type Error struct {
 	code       Code
 	underlying error
+	details    []*ErrorDetail
 }
 
 // NewError returns a new Error.
@@ -55,21 +61,29 @@ func NewErrorf(code Code, format string, args ...any) *Error {
 
 // NewErrorForProto returns a new Error for the given pluginrpcv1beta1.Error.
 //
+// Any ErrorDetails sent by the plugin are also attached to the returned Error.
+//
 // If protoError is nil, this returns nil.
 func NewErrorForProto(protoError *pluginrpcv1beta1.Error) *Error {
 	if protoError == nil {
 		return nil
 	}
-	return NewError(
+	pluginrpcError := NewError(
 		CodeForProto(protoError.GetCode()),
 		errors.New(protoError.GetMessage()),
 	)
+	for _, protoAny := range errorDetailsForProto(protoError) {
+		pluginrpcError.AddDetail(&ErrorDetail{protoAny: protoAny})
+	}
+	return pluginrpcError
 }
 
 // WrapError wraps the given error as a Error.
 //
 // If the given error is nil, this returns nil.
 // If the given error is already a Error, this is returned.
+// If the given error is context.Canceled or context.DeadlineExceeded, an error with
+// code CodeCanceled or CodeDeadlineExceeded is returned.
 // Otherwise, an error with code CodeUnknown is returned.
 //
 // An Error will never have an invalid Code when returned from this function.
@@ -81,6 +95,9 @@ func WrapError(err error) *Error {
 	if errors.As(err, &pluginrpcError) {
 		return validateError(pluginrpcError)
 	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return NewError(codeForContextError(err), err)
+	}
 	return NewError(CodeUnknown, err)
 }
 
@@ -94,6 +111,35 @@ func (e *Error) Code() Code {
 	return e.code
 }
 
+// Details returns the ErrorDetails attached to the Error.
+//
+// ErrorDetails are only received from plugins that respond in the binary format, which
+// plugins built with this library do for calls made by a Client. ErrorDetails are never
+// received from plugins that respond in JSON, including plugins that only support older
+// protocol versions. Plugins built with this library write a warning to stderr when they
+// drop ErrorDetails.
+//
+// If e is nil, this returns nil.
+func (e *Error) Details() []*ErrorDetail {
+	if e == nil {
+		return nil
+	}
+	return slices.Clone(e.details)
+}
+
+// AddDetail attaches an ErrorDetail to the Error.
+//
+// ErrorDetails are only sent to clients that use the binary format. They are dropped
+// when the error is written as JSON, which includes when the plugin is invoked on the
+// command line, and when the client only supports older protocol versions. In that case,
+// the plugin writes a warning naming the types of the dropped ErrorDetails to stderr.
+func (e *Error) AddDetail(errorDetail *ErrorDetail) {
+	if e == nil || errorDetail == nil {
+		return
+	}
+	e.details = append(e.details, errorDetail)
+}
+
 // ToProto converts the Error to a pluginrpcv1beta1.Error.
 //
 // If e is nil, this returns nil.
@@ -102,10 +148,12 @@ func (e *Error) ToProto() *pluginrpcv1beta1.Error {
 		return nil
 	}
 	pluginrpcError := validateError(e)
-	return &pluginrpcv1beta1.Error{
+	protoError := &pluginrpcv1beta1.Error{
 		Code:    pluginrpcError.Code().ToProto(),
 		Message: pluginrpcError.Unwrap().Error(),
 	}
+	setErrorDetailsOnProto(protoError, pluginrpcError.details)
+	return protoError
 }
 
 // Error implements error.
@@ -135,8 +183,127 @@ func (e *Error) Unwrap() error {
 	return e.underlying
 }
 
+// ErrorDetail is a typed Protobuf message attached to an Error.
+//
+// ErrorDetails allow plugins to send structured information about an error to clients,
+// in addition to the Code and message.
+type ErrorDetail struct {
+	protoAny *anypb.Any
+}
+
+// NewErrorDetail returns a new ErrorDetail for the given message.
+func NewErrorDetail(message proto.Message) (*ErrorDetail, error) {
+	protoAny, err := anypb.New(message)
+	if err != nil {
+		return nil, err
+	}
+	return &ErrorDetail{
+		protoAny: protoAny,
+	}, nil
+}
+
+// Type returns the fully-qualified name of the message type of the detail,
+// for example "google.protobuf.StringValue".
+func (d *ErrorDetail) Type() string {
+	return string(d.protoAny.MessageName())
+}
+
+// Bytes returns the binary-encoded message of the detail.
+func (d *ErrorDetail) Bytes() []byte {
+	return slices.Clone(d.protoAny.GetValue())
+}
+
+// Value decodes the detail into a new message of the detail's type.
+//
+// The message type must be linked into the binary, that is registered in
+// protoregistry.GlobalTypes.
+func (d *ErrorDetail) Value() (proto.Message, error) {
+	return d.protoAny.UnmarshalNew()
+}
+
+// UnmarshalTo decodes the detail into the given message.
+//
+// An error is returned if the message is not of the detail's type.
+func (d *ErrorDetail) UnmarshalTo(message proto.Message) error {
+	return d.protoAny.UnmarshalTo(message)
+}
+
+// HasPartialResponse returns true if the error was returned by a plugin alongside a response.
+//
+// Handlers can return a response and an error together, for example to return partial
+// results along with an error describing what failed. In this case, the response is
+// populated, and the returned error wraps an *Error and HasPartialResponse returns true.
+// Generated clients return both the response and the error in this case.
+func HasPartialResponse(err error) bool {
+	partialResponseError := &partialResponseError{}
+	return errors.As(err, &partialResponseError)
+}
+
 // *** PRIVATE ***
 
+// partialResponseError wraps an error that was returned alongside a response.
+type partialResponseError struct {
+	underlying error
+}
+
+func newPartialResponseError(underlying error) *partialResponseError {
+	return &partialResponseError{
+		underlying: underlying,
+	}
+}
+
+func (p *partialResponseError) Error() string {
+	return p.underlying.Error()
+}
+
+func (p *partialResponseError) Unwrap() error {
+	return p.underlying
+}
+
+// setErrorDetailsOnProto encodes the ErrorDetails as unknown fields on the protoError.
+//
+// The upstream Error message does not have a field for details, so the details are
+// encoded as the fields of the errorv1.ErrorExtension message. These are preserved by
+// the binary format, and dropped by the JSON format. If the upstream Error message ever
+// defines a field with the same number, the details are dropped rather than sent as a
+// malformed field.
+func setErrorDetailsOnProto(protoError *pluginrpcv1beta1.Error, errorDetails []*ErrorDetail) {
+	if len(errorDetails) == 0 || !isExtensionOf(protoError, &errorv1.ErrorExtension{}) {
+		return
+	}
+	errorExtension := &errorv1.ErrorExtension{}
+	for _, errorDetail := range errorDetails {
+		errorExtension.Details = append(errorExtension.Details, errorDetail.protoAny)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(errorExtension)
+	if err != nil {
+		// Any messages only contain a string and bytes, so this cannot happen in practice.
+		// The details are best-effort, we still want to send the error.
+		return
+	}
+	protoError.ProtoReflect().SetUnknown(data)
+}
+
+// errorDetailsForProto decodes the details encoded by setErrorDetailsOnProto.
+//
+// Malformed details are ignored.
+func errorDetailsForProto(protoError *pluginrpcv1beta1.Error) []*anypb.Any {
+	unknown := protoError.ProtoReflect().GetUnknown()
+	if len(unknown) == 0 || !isExtensionOf(protoError, &errorv1.ErrorExtension{}) {
+		return nil
+	}
+	errorExtension := &errorv1.ErrorExtension{}
+	if err := proto.Unmarshal(unknown, errorExtension); err != nil {
+		return nil
+	}
+	return errorExtension.GetDetails()
+}
+
+// newPanicError returns a new *Error with CodeInternal for the value recovered from a panic.
+func newPanicError(recovered any) *Error {
+	return NewErrorf(CodeInternal, "panic: %v", recovered)
+}
+
 func validateError(pluginrpcError *Error) *Error {
 	code := pluginrpcError.Code()
 	underlying := pluginrpcError.Unwrap()
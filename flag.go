@@ -15,13 +15,36 @@
 package pluginrpc
 
 import (
+	"bytes"
+	"fmt"
+	"strconv"
+
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
 const (
-	protocolVersion    = 1
+	// protocolVersion is the base protocol version, which all plugins support.
+	protocolVersion = 1
+	// protocolVersionServe is the protocol version that added support for `--plugin-serve`.
+	protocolVersionServe = 2
+	// protocolVersionBinary is the protocol version that added support for the binary format.
+	protocolVersionBinary = 3
+	// protocolVersionHeader is the protocol version that added support for header frames.
+	protocolVersionHeader = 4
+	// protocolVersionSpecID is the protocol version that added support for spec IDs.
+	protocolVersionSpecID = 5
+	// protocolVersionReflection is the protocol version that added support for `--plugin-reflect`.
+	protocolVersionReflection = 6
+	// maxProtocolVersion is the maximum protocol version supported by this library.
+	maxProtocolVersion = protocolVersionReflection
+
 	flagProtocolSuffix = "plugin-protocol"
 	flagSpecSuffix     = "plugin-spec"
+	flagServeSuffix    = "plugin-serve"
+	flagSpecIDSuffix   = "plugin-spec-id"
+	flagReflectSuffix  = "plugin-reflect"
+	flagInputSuffix    = "plugin-input"
+	flagOutputSuffix   = "plugin-output"
 )
 
 func marshalFlag(value any) ([]byte, error) {
@@ -40,6 +63,27 @@ func unmarshalFlag(data []byte, value any) error {
 	return protojson.Unmarshal(data, message)
 }
 
+// negotiateProtocolVersion returns the protocol version to use given the data
+// sent on stdin to `--plugin-protocol`.
+//
+// Clients that support protocol versions beyond the base protocol version send the
+// maximum protocol version they support on stdin. Older clients send nothing, in which
+// case the base protocol version is used.
+func negotiateProtocolVersion(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return protocolVersion, nil
+	}
+	clientMaxProtocolVersion, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol version sent on stdin: %w", err)
+	}
+	if clientMaxProtocolVersion < protocolVersion {
+		return 0, fmt.Errorf("invalid protocol version sent on stdin: %d", clientMaxProtocolVersion)
+	}
+	return min(clientMaxProtocolVersion, maxProtocolVersion), nil
+}
+
 func fullFlag(prefix string, suffix string) string {
 	if prefix == "" {
 		return "--" + suffix
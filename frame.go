@@ -0,0 +1,259 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	headerv1 "github.com/bufbuild/pluginrpc-go/internal/gen/buf/pluginrpc/header/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// format is a wire format for requests and responses.
+type format int
+
+const (
+	// formatJSON is the JSON wire format, which is the default.
+	//
+	// This is the format used when invoking a plugin as a CLI.
+	formatJSON format = iota + 1
+	// formatBinary is the binary protobuf wire format.
+	//
+	// This is only used by clients if the plugin supports protocolVersionBinary.
+	formatBinary
+)
+
+const (
+	// frameDelimiter is the delimiter between JSON frames in a stream.
+	//
+	// JSON-encoded messages never contain a raw newline, as newlines within strings
+	// are always escaped, so a newline is an unambiguous delimiter. This also has the
+	// nice property that streams are readable when a plugin is invoked as a CLI.
+	frameDelimiter = '\n'
+	// binaryFrameMarker is the first byte of every binary frame.
+	//
+	// A binary frame is the marker, followed by the varint-encoded length of the data,
+	// followed by the data. A JSON frame can never begin with a zero byte, which allows
+	// readers to determine the format of each frame.
+	binaryFrameMarker = 0x00
+	// headerFrameMarker is the first byte of a header frame.
+	//
+	// A header frame is the marker, followed by the varint-encoded length of the data,
+	// followed by a binary-encoded Header. Header frames are only ever the first frame
+	// of a stream, and are only sent with protocolVersionHeader.
+	headerFrameMarker = 0x01
+)
+
+// String implements fmt.Stringer.
+func (f format) String() string {
+	switch f {
+	case formatJSON:
+		return "json"
+	case formatBinary:
+		return "binary"
+	default:
+		return fmt.Sprintf("format(%d)", int(f))
+	}
+}
+
+// encodeFrame encodes the data as a single frame in the given format.
+func encodeFrame(format format, data []byte) []byte {
+	if format == formatBinary {
+		return encodeLengthPrefixedFrame(binaryFrameMarker, data)
+	}
+	return append(data, frameDelimiter)
+}
+
+// encodeHeaderFrame encodes the header as a header frame.
+func encodeHeaderFrame(header Header) ([]byte, error) {
+	data, err := proto.Marshal(headerToProto(header))
+	if err != nil {
+		return nil, err
+	}
+	return encodeLengthPrefixedFrame(headerFrameMarker, data), nil
+}
+
+// writeFrame writes a single frame in the given format to the writer.
+func writeFrame(writer io.Writer, format format, data []byte) error {
+	_, err := writer.Write(encodeFrame(format, data))
+	return err
+}
+
+// decodeFrame decodes data that contains at most a single frame, for example
+// the entire stdin of a unary call.
+//
+// If the data is not a binary frame, the data is assumed to be JSON, which may span
+// multiple lines. This allows users to pipe arbitrary JSON to a plugin invoked as a CLI.
+func decodeFrame(data []byte) ([]byte, format, error) {
+	if len(data) == 0 || data[0] != binaryFrameMarker {
+		return data, formatJSON, nil
+	}
+	data, rest, err := splitLengthPrefixedFrame(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rest = bytes.TrimSpace(rest); len(rest) > 0 {
+		return nil, 0, errors.New("unexpected data after binary frame")
+	}
+	return data, formatBinary, nil
+}
+
+// decodeHeaderFrame decodes the header frame at the start of data, if present.
+//
+// Returns the Header, or nil if there was no header frame, along with the remaining data.
+func decodeHeaderFrame(data []byte) (Header, []byte, error) {
+	if len(data) == 0 || data[0] != headerFrameMarker {
+		return nil, data, nil
+	}
+	headerData, rest, err := splitLengthPrefixedFrame(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := unmarshalHeader(headerData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, rest, nil
+}
+
+// frameReader reads frames written by writeFrame.
+//
+// The format of each frame is determined independently.
+type frameReader struct {
+	reader *bufio.Reader
+}
+
+func newFrameReader(reader io.Reader) *frameReader {
+	return &frameReader{
+		reader: bufio.NewReader(reader),
+	}
+}
+
+// Next returns the next frame, along with the format of the frame.
+//
+// Empty lines are skipped. Returns io.EOF if there are no more frames.
+func (f *frameReader) Next() ([]byte, format, error) {
+	for {
+		first, err := f.reader.Peek(1)
+		if err != nil {
+			return nil, 0, err
+		}
+		if first[0] == binaryFrameMarker {
+			return f.nextBinary()
+		}
+		data, err := f.reader.ReadBytes(frameDelimiter)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			// If we got data along with io.EOF, the last frame did not end in a delimiter.
+			// We return the frame, and the next call will return io.EOF.
+			return data, formatJSON, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// NextHeader returns the header frame at the current position, if present.
+//
+// Returns nil if the next frame is not a header frame, or if there are no more frames.
+// This should only be called before the first call to Next.
+func (f *frameReader) NextHeader() (Header, error) {
+	first, err := f.reader.Peek(1)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if first[0] != headerFrameMarker {
+		return nil, nil
+	}
+	data, err := f.readLengthPrefixed()
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalHeader(data)
+}
+
+func (f *frameReader) nextBinary() ([]byte, format, error) {
+	data, err := f.readLengthPrefixed()
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, formatBinary, nil
+}
+
+// readLengthPrefixed reads a frame consisting of a marker byte, followed by the
+// varint-encoded length of the data, followed by the data.
+func (f *frameReader) readLengthPrefixed() ([]byte, error) {
+	if _, err := f.reader.Discard(1); err != nil {
+		return nil, err
+	}
+	length, err := binary.ReadUvarint(f.reader)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(f.reader, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return data, nil
+}
+
+func encodeLengthPrefixedFrame(marker byte, data []byte) []byte {
+	frame := make([]byte, 0, 1+binary.MaxVarintLen64+len(data))
+	frame = append(frame, marker)
+	frame = binary.AppendUvarint(frame, uint64(len(data)))
+	return append(frame, data...)
+}
+
+// splitLengthPrefixedFrame splits the length-prefixed frame at the start of data
+// from the remaining data.
+func splitLengthPrefixedFrame(data []byte) ([]byte, []byte, error) {
+	length, lengthSize := binary.Uvarint(data[1:])
+	if lengthSize <= 0 {
+		return nil, nil, errors.New("malformed frame length")
+	}
+	data = data[1+lengthSize:]
+	if uint64(len(data)) < length {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	return data[:length], data[length:], nil
+}
+
+func unmarshalHeader(data []byte) (Header, error) {
+	protoHeader := &headerv1.Header{}
+	if err := proto.Unmarshal(data, protoHeader); err != nil {
+		return nil, err
+	}
+	return headerForProto(protoHeader), nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, as io.EOF is only expected
+// between frames.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -16,9 +16,12 @@ package pluginrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"runtime/debug"
+	"strings"
 
 	"github.com/mattn/go-isatty"
 )
@@ -27,8 +30,7 @@ import (
 //
 // This is used within generated code when registering an implementation of a service.
 //
-// Currently, Handlers do not have any customization, however this type is exposes
-// so that customization can be provided in the future.
+// Handlers can be customized with HandlerOptions, for example to add UnaryInterceptors.
 type Handler interface {
 	Handle(
 		ctx context.Context,
@@ -36,24 +38,96 @@ type Handler interface {
 		request any,
 		handle func(context.Context, any) (any, error),
 	) error
+	// HandleServerStream handles a server-streaming Procedure.
+	//
+	// The handle function is given a send function, which will write each response
+	// to stdout as a separate frame.
+	HandleServerStream(
+		ctx context.Context,
+		env Env,
+		request any,
+		handle func(context.Context, any, func(any) error) error,
+	) error
+	// HandleClientStream handles a client-streaming Procedure.
+	//
+	// The handle function is given a receive function, which will read the next request
+	// frame from stdin and populate the given request. The receive function returns
+	// io.EOF once all requests have been read.
+	HandleClientStream(
+		ctx context.Context,
+		env Env,
+		handle func(context.Context, func(any) error) (any, error),
+	) error
 
 	isHandler()
 }
 
 // NewHandler returns a new Handler.
-func NewHandler(...HandlerOption) Handler {
-	return newHandler()
+func NewHandler(options ...HandlerOption) Handler {
+	return newHandler(options...)
 }
 
 // HandlerOption is an option for a new Handler.
 type HandlerOption func(*handlerOptions)
 
+// HandlerWithValidator will result in requests being validated with the given Validator
+// before they are given to the handle function, and responses being validated before they
+// are written.
+//
+// Invalid requests result in an *Error with CodeInvalidArgument, and invalid responses
+// result in an *Error with CodeInternal. For unary calls, validation happens within any
+// UnaryInterceptors.
+//
+// The default is to not validate requests and responses.
+func HandlerWithValidator(validator Validator) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.validator = validator
+	}
+}
+
+// HandlerWithPanicStack will result in the stack trace being included in the message of the
+// *Error written when a handle function panics.
+//
+// Stack traces reveal details about the implementation of the plugin, so this should
+// generally only be used for debugging.
+//
+// The default is to only include the value the handle function panicked with.
+func HandlerWithPanicStack() HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.panicStack = true
+	}
+}
+
+// HandlerWithInterceptors will result in the given UnaryInterceptors wrapping every unary call.
+//
+// The first UnaryInterceptor is the outermost, that is, it is called first, and sees the
+// final response and error.
+//
+// UnaryInterceptors do not apply to streaming calls.
+func HandlerWithInterceptors(interceptors ...UnaryInterceptor) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.interceptors = append(handlerOptions.interceptors, interceptors...)
+	}
+}
+
 // *** PRIVATE ***
 
-type handler struct{}
+type handler struct {
+	interceptors []UnaryInterceptor
+	panicStack   bool
+	validator    Validator
+}
 
-func newHandler() *handler {
-	return &handler{}
+func newHandler(options ...HandlerOption) *handler {
+	handlerOptions := newHandlerOptions()
+	for _, option := range options {
+		option(handlerOptions)
+	}
+	return &handler{
+		interceptors: handlerOptions.interceptors,
+		panicStack:   handlerOptions.panicStack,
+		validator:    handlerOptions.validator,
+	}
 }
 
 func (h *handler) Handle(
@@ -62,54 +136,368 @@ func (h *handler) Handle(
 	request any,
 	handle func(context.Context, any) (any, error),
 ) (retErr error) {
+	cliOptions := cliOptionsFromContext(ctx)
+	responseWriter := newResponseWriter(env.Stdout, env.Stderr)
+	responseWriter.output = cliOptions.output
 	defer func() {
-		if retErr != nil {
-			retErr = h.writeError(env, retErr)
+		retErr = h.writeError(responseWriter, retErr, recover())
+	}()
+
+	requestHeader, err := readRequest(env, request, responseWriter, cliOptions.input)
+	if err != nil {
+		return err
+	}
+	if err := setRequestFlags(request, cliOptions.requestFlags); err != nil {
+		return err
+	}
+	ctx, cancel, err := withRequestTimeout(ctx, requestHeader)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	ctx = withLogger(ctx, env.Stderr, requestHeader)
+	unaryFunc := chainUnaryInterceptors(
+		func(ctx context.Context, _ string, request any) (any, error) {
+			if err := validateMessage(h.validator, request, CodeInvalidArgument); err != nil {
+				return nil, err
+			}
+			response, err := handle(ctx, request)
+			if err == nil {
+				if err := validateMessage(h.validator, response, CodeInternal); err != nil {
+					return nil, err
+				}
+			}
+			return response, err
+		},
+		h.interceptors,
+	)
+	response, err := unaryFunc(
+		withResponseHeader(ctx, responseWriter.header),
+		procedurePathFromContext(ctx),
+		request,
+	)
+	if err != nil {
+		if isNilResponse(response) {
+			return err
 		}
+		// The handler returned a partial response alongside the error, so we write both.
+		return responseWriter.WriteResponseAndError(response, err)
+	}
+	return responseWriter.WriteResponse(response)
+}
+
+func (h *handler) HandleServerStream(
+	ctx context.Context,
+	env Env,
+	request any,
+	handle func(context.Context, any, func(any) error) error,
+) (retErr error) {
+	cliOptions := cliOptionsFromContext(ctx)
+	responseWriter := newResponseWriter(env.Stdout, env.Stderr)
+	responseWriter.output = cliOptions.output
+	defer func() {
+		retErr = h.writeError(responseWriter, retErr, recover())
 	}()
 
-	data, err := readStdin(env.Stdin)
+	requestHeader, err := readRequest(env, request, responseWriter, cliOptions.input)
+	if err != nil {
+		return err
+	}
+	if err := setRequestFlags(request, cliOptions.requestFlags); err != nil {
+		return err
+	}
+	ctx, cancel, err := withRequestTimeout(ctx, requestHeader)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	ctx = withLogger(ctx, env.Stderr, requestHeader)
+	if err := validateMessage(h.validator, request, CodeInvalidArgument); err != nil {
+		return err
+	}
+	return handle(
+		withResponseHeader(ctx, responseWriter.header),
+		request,
+		func(response any) error {
+			if err := validateMessage(h.validator, response, CodeInternal); err != nil {
+				return err
+			}
+			return responseWriter.WriteResponse(response)
+		},
+	)
+}
+
+func (h *handler) HandleClientStream(
+	ctx context.Context,
+	env Env,
+	handle func(context.Context, func(any) error) (any, error),
+) (retErr error) {
+	// The format is determined by the request frames. If there are no request
+	// frames, this defaults to JSON.
+	cliOptions := cliOptionsFromContext(ctx)
+	responseWriter := newResponseWriter(env.Stdout, env.Stderr)
+	responseWriter.output = cliOptions.output
+	defer func() {
+		retErr = h.writeError(responseWriter, retErr, recover())
+	}()
+
+	if len(cliOptions.requestFlags) > 0 {
+		return NewError(CodeInvalidArgument, errors.New("request flags are not supported for client-streaming procedures"))
+	}
+	if cliOptions.input != "" {
+		responseWriter.setBareRequest()
+		return NewError(CodeInvalidArgument, errors.New("request input is not supported for client-streaming procedures"))
+	}
+
+	stdin := env.Stdin
+	if stdin == nil {
+		stdin = discardReader{}
+	}
+	frameReader := newFrameReader(stdin)
+	requestHeader, err := frameReader.NextHeader()
+	if err != nil {
+		return err
+	}
+	responseWriter.sendHeader = requestHeader != nil
+	ctx, cancel, err := withRequestTimeout(ctx, requestHeader)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	ctx = withLogger(ctx, env.Stderr, requestHeader)
+	response, err := handle(
+		withResponseHeader(ctx, responseWriter.header),
+		func(request any) error {
+			data, format, err := frameReader.Next()
+			if err != nil {
+				return err
+			}
+			responseWriter.format = format
+			isBareRequest, err := unmarshalRequestOrBareRequest(format, data, request)
+			if isBareRequest {
+				responseWriter.setBareRequest()
+			}
+			if err != nil {
+				return err
+			}
+			return validateMessage(h.validator, request, CodeInvalidArgument)
+		},
+	)
 	if err != nil {
 		return err
 	}
-	if err := unmarshalRequest(data, request); err != nil {
+	if err := validateMessage(h.validator, response, CodeInternal); err != nil {
+		return err
+	}
+	return responseWriter.WriteResponse(response)
+}
+
+func (*handler) isHandler() {}
+
+// writeError writes the error returned by a handle function, or the value recovered from a
+// panic in the handle function, to stdout.
+//
+// Panics are written as an *Error with CodeInternal. As the plugin did not complete normally,
+// an *ExitError is then returned, so that the plugin exits with a non-zero exit code. Clients
+// still return the written *Error.
+func (h *handler) writeError(responseWriter *responseWriter, err error, recovered any) error {
+	if recovered == nil {
+		return responseWriter.WriteError(err)
+	}
+	panicErr := newPanicError(recovered)
+	if h.panicStack {
+		panicErr = NewErrorf(CodeInternal, "%v\n\n%s", panicErr.Unwrap(), debug.Stack())
+	}
+	if err := responseWriter.WriteError(panicErr); err != nil {
 		return err
 	}
-	response, err := handle(ctx, request)
+	return NewExitError(exitCodeInternal, panicErr)
+}
+
+// readRequest reads the request from stdin, and returns the request Header, if any.
+//
+// The responseWriter is configured to respond in the same format as the request, and
+// to send a header frame if the client sent a header frame.
+//
+// If a cliInput is given, stdin is read as a bare request in the cliInput.
+func readRequest(env Env, request any, responseWriter *responseWriter, input cliInput) (Header, error) {
+	data, err := readStdin(env.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	if input != "" {
+		responseWriter.setBareRequest()
+		return nil, unmarshalCLIInput(input, data, request)
+	}
+	requestHeader, data, err := decodeHeaderFrame(data)
+	if err != nil {
+		return nil, err
+	}
+	responseWriter.sendHeader = requestHeader != nil
+	data, format, err := decodeFrame(data)
+	if err != nil {
+		return nil, err
+	}
+	responseWriter.format = format
+	isBareRequest, err := unmarshalRequestOrBareRequest(format, data, request)
+	if isBareRequest {
+		responseWriter.setBareRequest()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return requestHeader, nil
+}
+
+// responseWriter writes responses and errors to stdout.
+type responseWriter struct {
+	stdout io.Writer
+	// stderr is where a warning is written if ErrorDetails cannot be written, see
+	// writeDroppedErrorDetailsWarning.
+	stderr io.Writer
+	// format is the format to write responses in, defaulting to JSON.
+	format format
+	// output is the cliOutput given on the command line, if any.
+	//
+	// If set, only response bodies are written to stdout, and errors are returned instead
+	// of being written, so that the plugin exits with a non-zero exit code.
+	output cliOutput
+	// header is the response Header, which is written before the first response or
+	// error if sendHeader is true.
+	header     Header
+	sendHeader bool
+	wroteFrame bool
+}
+
+func newResponseWriter(stdout io.Writer, stderr io.Writer) *responseWriter {
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	return &responseWriter{
+		stdout: stdout,
+		stderr: stderr,
+		format: formatJSON,
+		header: make(Header),
+	}
+}
+
+// WriteResponse writes the response as a single frame.
+//
+// JSON frames are newline-delimited, so that the server will behave nicely as a CLI.
+func (r *responseWriter) WriteResponse(response any) error {
+	if r.output != "" {
+		return r.writeCLIOutput(response)
+	}
+	data, err := marshalResponse(r.format, response, nil)
 	if err != nil {
-		// TODO: This results in writeError being called, but ignores marshaling
-		// the response, so we will never have a non-nil response and non-nil
-		// error together, which the protocol says we can have.
-		//
-		// This just needs some refactoring.
 		return err
 	}
-	data, err = marshalResponse(response, nil)
+	if err := r.writeFrame(data); err != nil {
+		return fmt.Errorf("failed to write response to stdout: %w", err)
+	}
+	return nil
+}
+
+// WriteResponseAndError writes the response and the error together as a single frame.
+func (r *responseWriter) WriteResponseAndError(response any, inputErr error) error {
+	r.writeDroppedErrorDetailsWarning(inputErr)
+	if r.output != "" {
+		if err := r.writeCLIOutput(response); err != nil {
+			return err
+		}
+		return inputErr
+	}
+	data, err := marshalResponse(r.format, response, inputErr)
 	if err != nil {
 		return err
 	}
-	// We append a newline so that the server will behave nicely as a CLI.
-	if _, err = env.Stdout.Write(append(data, []byte("\n")...)); err != nil {
+	if err := r.writeFrame(data); err != nil {
 		return fmt.Errorf("failed to write response to stdout: %w", err)
 	}
-	return err
+	return nil
 }
 
-func (h *handler) writeError(env Env, inputErr error) error {
+// WriteError writes the error as a single frame.
+//
+// If an output was given on the command line, the error is returned instead.
+func (r *responseWriter) WriteError(inputErr error) error {
 	if inputErr == nil {
 		return nil
 	}
-	data, err := marshalResponse(nil, inputErr)
+	r.writeDroppedErrorDetailsWarning(inputErr)
+	if r.output != "" {
+		return inputErr
+	}
+	data, err := marshalResponse(r.format, nil, inputErr)
 	if err != nil {
 		return err
 	}
-	if _, err := env.Stdout.Write(data); err != nil {
+	if err := r.writeFrame(data); err != nil {
 		return fmt.Errorf("failed to write error to stdout: %w", err)
 	}
 	return nil
 }
 
-func (*handler) isHandler() {}
+// setBareRequest configures the responseWriter for a request given as a bare request.
+//
+// Bare requests are only given on the command line, so responses are written as
+// indented JSON unless another cliOutput was given.
+func (r *responseWriter) setBareRequest() {
+	if r.output == "" {
+		r.output = cliOutputPrettyJSON
+	}
+}
+
+// writeDroppedErrorDetailsWarning writes a warning to stderr if the error has ErrorDetails
+// that will be dropped, so that the details do not silently disappear.
+//
+// ErrorDetails can only be written in the binary format. They are dropped when the error is
+// written as JSON, or returned instead of written because a cliOutput was given. This is the
+// case when the plugin is invoked on the command line, or by a client that only supports
+// older protocol versions.
+func (r *responseWriter) writeDroppedErrorDetailsWarning(inputErr error) {
+	if r.format == formatBinary && r.output == "" {
+		return
+	}
+	pluginrpcError := &Error{}
+	if !errors.As(inputErr, &pluginrpcError) || len(pluginrpcError.details) == 0 {
+		return
+	}
+	types := make([]string, len(pluginrpcError.details))
+	for i, errorDetail := range pluginrpcError.details {
+		types[i] = errorDetail.Type()
+	}
+	_, _ = fmt.Fprintf(
+		r.stderr,
+		"warning: dropped error details that cannot be written as JSON, call the plugin with a pluginrpc Client to receive them: %s\n",
+		strings.Join(types, ", "),
+	)
+}
+
+func (r *responseWriter) writeCLIOutput(response any) error {
+	data, err := marshalCLIOutput(r.output, response)
+	if err != nil {
+		return err
+	}
+	if _, err := r.stdout.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write response to stdout: %w", err)
+	}
+	return nil
+}
+
+func (r *responseWriter) writeFrame(data []byte) error {
+	if !r.wroteFrame && r.sendHeader && len(r.header) > 0 {
+		headerFrame, err := encodeHeaderFrame(r.header)
+		if err != nil {
+			return err
+		}
+		if _, err := r.stdout.Write(headerFrame); err != nil {
+			return err
+		}
+	}
+	r.wroteFrame = true
+	return writeFrame(r.stdout, r.format, data)
+}
 
 // readStdin handles stdin specially to determine if stdin is a *os.File (likely os.Stdin)
 // and is itself a terminal. If so, we don't block on io.ReadAll, as we know that there
@@ -123,6 +511,9 @@ func (*handler) isHandler() {}
 //
 //	plugin-server /pkg.Service/Method
 func readStdin(stdin io.Reader) ([]byte, error) {
+	if stdin == nil {
+		return nil, nil
+	}
 	file, ok := stdin.(*os.File)
 	if ok {
 		if isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd()) {
@@ -133,4 +524,12 @@ func readStdin(stdin io.Reader) ([]byte, error) {
 	return io.ReadAll(stdin)
 }
 
-type handlerOptions struct{}
+type handlerOptions struct {
+	interceptors []UnaryInterceptor
+	panicStack   bool
+	validator    Validator
+}
+
+func newHandlerOptions() *handlerOptions {
+	return &handlerOptions{}
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	headerv1 "github.com/bufbuild/pluginrpc-go/internal/gen/buf/pluginrpc/header/v1"
+)
+
+// Header is a set of key-value metadata sent alongside responses.
+//
+// Keys are case-sensitive.
+type Header map[string][]string
+
+// Get returns the first value for the key, or the empty string if there is no value.
+func (h Header) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns all values for the key.
+func (h Header) Values(key string) []string {
+	return h[key]
+}
+
+// Set sets the value for the key, replacing any existing values.
+func (h Header) Set(key string, value string) {
+	h[key] = []string{value}
+}
+
+// Add adds the value for the key, appending to any existing values.
+func (h Header) Add(key string, value string) {
+	h[key] = append(h[key], value)
+}
+
+// Del deletes all values for the key.
+func (h Header) Del(key string) {
+	delete(h, key)
+}
+
+// ResponseHeaderFromContext returns the response Header for the call being handled.
+//
+// This is meant to be used within handlers. The Header must be modified before the
+// first response is sent, as the Header is written before any responses. If the client
+// does not support headers, modifications to the Header are discarded.
+//
+// If the context is not the context of a call being handled, this returns a new Header,
+// and modifications to the Header are discarded.
+func ResponseHeaderFromContext(ctx context.Context) Header {
+	header, ok := ctx.Value(responseHeaderContextKey{}).(Header)
+	if !ok {
+		return make(Header)
+	}
+	return header
+}
+
+// *** PRIVATE ***
+
+// headerKeySpecID is the key of the header field containing the spec ID of a plugin.
+//
+// Plugins send this in response to `--plugin-spec`, and in response to a call if the
+// spec ID sent with the call does not match the current spec ID of the plugin.
+const headerKeySpecID = "pluginrpc-spec-id"
+
+// The keys of the header fields that carry the properties of a Spec that
+// pluginrpcv1beta1.Spec cannot represent, as pluginrpcv1beta1.Spec only specifies the path
+// and args of each Procedure.
+//
+// This spec header is the only channel for these properties. Plugins send it in a header
+// frame before the Spec in response to `--plugin-spec`, if the client sent a header frame
+// on stdin, which requires both to support spec IDs. The Spec itself is always JSON, so
+// that the output of `--plugin-spec` stays readable on the command line, where the
+// properties are shown by `--help` instead. See newSpecHeader and newSpecForProto.
+const (
+	// headerKeySpecName, headerKeySpecVersion, and headerKeySpecDescription contain the
+	// name, version, and description of the plugin.
+	headerKeySpecName        = "pluginrpc-spec-name"
+	headerKeySpecVersion     = "pluginrpc-spec-version"
+	headerKeySpecDescription = "pluginrpc-spec-description"
+	// headerKeyIdempotentProcedure contains the paths of the idempotent Procedures.
+	headerKeyIdempotentProcedure = "pluginrpc-idempotent-procedure"
+	// headerKeyDeprecatedProcedure contains the paths of the deprecated Procedures.
+	headerKeyDeprecatedProcedure = "pluginrpc-deprecated-procedure"
+	// headerKeyClientStreamProcedure and headerKeyServerStreamProcedure contain the paths
+	// of the Procedures with StreamTypeClient and StreamTypeServer.
+	headerKeyClientStreamProcedure = "pluginrpc-client-stream-procedure"
+	headerKeyServerStreamProcedure = "pluginrpc-server-stream-procedure"
+	// headerKeyProcedureDescription contains the descriptions of the Procedures. Each value
+	// is the path of a Procedure, followed by a newline and the description of the
+	// Procedure. Paths cannot contain newlines.
+	headerKeyProcedureDescription = "pluginrpc-procedure-description"
+)
+
+// headerKeyTimeout is the key of the request header field containing the time remaining
+// until the deadline of the call, in milliseconds.
+//
+// Plugins apply the timeout to the context of the call. A relative timeout is sent rather
+// than an absolute deadline, so that the deadline is not affected by clock differences.
+const headerKeyTimeout = "pluginrpc-timeout-ms"
+
+type responseHeaderContextKey struct{}
+
+func withResponseHeader(ctx context.Context, header Header) context.Context {
+	return context.WithValue(ctx, responseHeaderContextKey{}, header)
+}
+
+// encodeTimeout encodes the timeout as the value of headerKeyTimeout.
+//
+// The timeout is rounded up, so that a remaining timeout is never encoded as 0.
+func encodeTimeout(timeout time.Duration) string {
+	milliseconds := (timeout + time.Millisecond - 1) / time.Millisecond
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+	return strconv.FormatInt(int64(milliseconds), 10)
+}
+
+// withRequestTimeout returns a context with the timeout sent by the client applied, if any.
+func withRequestTimeout(ctx context.Context, requestHeader Header) (context.Context, context.CancelFunc, error) {
+	value := requestHeader.Get(headerKeyTimeout)
+	if value == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	milliseconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || milliseconds < 1 {
+		return nil, nil, NewErrorf(CodeInvalidArgument, "invalid %s header value %q", headerKeyTimeout, value)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(milliseconds)*time.Millisecond)
+	return ctx, cancel, nil
+}
+
+func headerToProto(header Header) *headerv1.Header {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	// Sort so that the encoding is deterministic.
+	slices.Sort(keys)
+	protoHeader := &headerv1.Header{}
+	for _, key := range keys {
+		protoHeader.Fields = append(
+			protoHeader.Fields,
+			&headerv1.Header_Field{
+				Key:    key,
+				Values: header[key],
+			},
+		)
+	}
+	return protoHeader
+}
+
+func headerForProto(protoHeader *headerv1.Header) Header {
+	header := make(Header, len(protoHeader.GetFields()))
+	for _, field := range protoHeader.GetFields() {
+		header[field.GetKey()] = append(header[field.GetKey()], field.GetValues()...)
+	}
+	return header
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	flagHelpShort = "-h"
+	flagHelp      = "--help"
+	// helpIndent is the indentation of nested lines in help output.
+	helpIndent = "  "
+)
+
+// isHelpFlag returns true if the arg requests help.
+func isHelpFlag(arg string) bool {
+	return arg == flagHelp || arg == flagHelpShort
+}
+
+// writeHelp writes the help for the Spec to the writer.
+//
+// The help starts with the name, version, and description of the plugin, if specified, and
+// then lists every Procedure, along with the args it is invoked with, its path, its
+// description, and its request and response types if known.
+func writeHelp(writer io.Writer, spec Spec) error {
+	buffer := bytes.NewBuffer(nil)
+	if nameAndVersion := strings.TrimSpace(spec.Name() + " " + spec.Version()); nameAndVersion != "" {
+		buffer.WriteString(nameAndVersion + "\n\n")
+	}
+	if description := strings.TrimSpace(spec.Description()); description != "" {
+		buffer.WriteString(description + "\n\n")
+	}
+	buffer.WriteString("Usage:\n")
+	buffer.WriteString(helpIndent + "<procedure> [flags]\n")
+	buffer.WriteString("\nProcedures:\n")
+	for i, procedure := range spec.Procedures() {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(helpIndent + procedureInvocation(procedure) + "\n")
+		writeProcedureDescription(buffer, procedure, helpIndent+helpIndent)
+		if err := writeProcedureDetails(buffer, procedure, helpIndent+helpIndent); err != nil {
+			return err
+		}
+	}
+	writeHelpFlags(buffer, "Print this help and exit. Pass after a procedure to print help for the procedure.")
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// writeProcedureHelp writes the help for a single Procedure to the writer.
+//
+// If the request type is known, the help lists the flags that set the fields of the request.
+func writeProcedureHelp(writer io.Writer, procedure Procedure, flagPrefix string) error {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("Usage:\n")
+	buffer.WriteString(helpIndent + procedureInvocation(procedure) + " [flags]\n\n")
+	if writeProcedureDescription(buffer, procedure, "") {
+		buffer.WriteString("\n")
+	}
+	if err := writeProcedureDetails(buffer, procedure, ""); err != nil {
+		return err
+	}
+	buffer.WriteString("\nFlags:\n")
+	tabWriter := tabwriter.NewWriter(buffer, 0, 0, 3, ' ', 0)
+	if methodDescriptor := procedure.MethodDescriptor(); methodDescriptor != nil && !methodDescriptor.IsStreamingClient() {
+		fields := methodDescriptor.Input().Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fieldDescriptor := fields.Get(i)
+			fmt.Fprintf(
+				tabWriter,
+				"%s--%s=<%s>\t%s\n",
+				helpIndent,
+				fieldDescriptor.Name(),
+				helpFieldType(fieldDescriptor),
+				helpFieldDescription(fieldDescriptor),
+			)
+		}
+	}
+	if methodDescriptor := procedure.MethodDescriptor(); methodDescriptor == nil || !methodDescriptor.IsStreamingClient() {
+		fmt.Fprintf(
+			tabWriter,
+			"%s%s=<%s|%s>\tRead the request message itself from stdin, instead of the request sent by clients.\n",
+			helpIndent,
+			fullFlag(flagPrefix, flagInputSuffix),
+			cliInputJSON,
+			cliInputBinary,
+		)
+	}
+	fmt.Fprintf(
+		tabWriter,
+		"%s%s=<%s|%s|%s>\tPrint the response body as JSON, indented JSON, or text, instead of the response expected by clients.\n",
+		helpIndent,
+		fullFlag(flagPrefix, flagOutputSuffix),
+		cliOutputJSON,
+		cliOutputPrettyJSON,
+		cliOutputText,
+	)
+	fmt.Fprintf(tabWriter, "%s%s, %s\tPrint this help and exit.\n", helpIndent, flagHelpShort, flagHelp)
+	if err := tabWriter.Flush(); err != nil {
+		return err
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// writeProcedureDescription writes the description of the Procedure, followed by a
+// deprecation notice if the Procedure is deprecated, with every line prefixed by the indent.
+//
+// Returns false if nothing was written.
+func writeProcedureDescription(buffer *bytes.Buffer, procedure Procedure, indent string) bool {
+	description := strings.TrimSpace(procedure.Description())
+	if procedure.Deprecated() {
+		description = strings.TrimSpace(description + "\nDeprecated.")
+	}
+	if description == "" {
+		return false
+	}
+	for _, line := range strings.Split(description, "\n") {
+		buffer.WriteString(strings.TrimRight(indent+line, " ") + "\n")
+	}
+	return true
+}
+
+// writeProcedureDetails writes the path and types of the Procedure, with every line
+// prefixed by the indent.
+func writeProcedureDetails(buffer *bytes.Buffer, procedure Procedure, indent string) error {
+	tabWriter := tabwriter.NewWriter(buffer, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tabWriter, "%sPath:\t%s\n", indent, procedure.Path())
+	if methodDescriptor := procedure.MethodDescriptor(); methodDescriptor != nil {
+		fmt.Fprintf(
+			tabWriter,
+			"%sRequest:\t%s\n",
+			indent,
+			helpMessageType(methodDescriptor.Input(), methodDescriptor.IsStreamingClient()),
+		)
+		fmt.Fprintf(
+			tabWriter,
+			"%sResponse:\t%s\n",
+			indent,
+			helpMessageType(methodDescriptor.Output(), methodDescriptor.IsStreamingServer()),
+		)
+	}
+	return tabWriter.Flush()
+}
+
+func writeHelpFlags(buffer *bytes.Buffer, helpDescription string) {
+	buffer.WriteString("\nFlags:\n")
+	buffer.WriteString(helpIndent + flagHelpShort + ", " + flagHelp + "   " + helpDescription + "\n")
+}
+
+// helpFieldType returns the type of the value of the flag for the field.
+func helpFieldType(fieldDescriptor protoreflect.FieldDescriptor) string {
+	switch {
+	case fieldDescriptor.IsMap():
+		return "json"
+	case fieldDescriptor.Enum() != nil:
+		return string(fieldDescriptor.Enum().FullName())
+	case fieldDescriptor.Message() != nil:
+		if isJSONStringMessage(fieldDescriptor.Message()) {
+			return string(fieldDescriptor.Message().FullName())
+		}
+		return "json"
+	default:
+		return fieldDescriptor.Kind().String()
+	}
+}
+
+func helpFieldDescription(fieldDescriptor protoreflect.FieldDescriptor) string {
+	description := "Set the " + string(fieldDescriptor.Name()) + " field of the request."
+	if fieldDescriptor.IsList() {
+		description += " Can be given more than once."
+	}
+	return description
+}
+
+// procedureInvocation returns the args a Procedure is invoked with.
+//
+// If the Procedure has no args, it is invoked with its path.
+func procedureInvocation(procedure Procedure) string {
+	if args := procedure.Args(); len(args) > 0 {
+		return strings.Join(args, " ")
+	}
+	return procedure.Path()
+}
+
+func helpMessageType(messageDescriptor protoreflect.MessageDescriptor, isStreaming bool) string {
+	if isStreaming {
+		return "stream " + string(messageDescriptor.FullName())
+	}
+	return string(messageDescriptor.FullName())
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"context"
+)
+
+// UnaryFunc is the generic signature of a unary call.
+//
+// On the client side, the request is the request given to Client.Call, and the returned
+// response populates the response given to Client.Call. On the server side, the request is
+// the request read from stdin, and the returned response is written to stdout.
+type UnaryFunc func(ctx context.Context, procedurePath string, request any) (any, error)
+
+// UnaryInterceptor wraps a UnaryFunc with additional logic.
+//
+// UnaryInterceptors can be used for logging, metrics, auth checks, request validation,
+// and the like. A UnaryInterceptor sees the procedure path, the request, and the response
+// and error returned by the next UnaryFunc in the chain. A UnaryInterceptor may also
+// short-circuit the call by not calling the next UnaryFunc at all.
+//
+// UnaryInterceptors only apply to unary calls, and not to streaming calls.
+type UnaryInterceptor func(next UnaryFunc) UnaryFunc
+
+// *** PRIVATE ***
+
+// chainUnaryInterceptors wraps the UnaryFunc with the given UnaryInterceptors.
+//
+// The first UnaryInterceptor is the outermost, that is, it is called first, and sees the
+// final response and error.
+func chainUnaryInterceptors(unaryFunc UnaryFunc, interceptors []UnaryInterceptor) UnaryFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		unaryFunc = interceptors[i](unaryFunc)
+	}
+	return unaryFunc
+}
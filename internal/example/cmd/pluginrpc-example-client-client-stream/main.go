@@ -0,0 +1,66 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/pluginrpc-go"
+	examplev1 "github.com/bufbuild/pluginrpc-go/internal/example/gen/buf/pluginrpc/example/v1"
+	"github.com/bufbuild/pluginrpc-go/internal/example/gen/buf/pluginrpc/example/v1/examplev1pluginrpc"
+)
+
+func main() {
+	if err := run(); err != nil {
+		if errString := err.Error(); errString != "" {
+			_, _ = os.Stderr.Write([]byte(errString + "\n"))
+		}
+		os.Exit(pluginrpc.WrapExitError(err).ExitCode())
+	}
+}
+
+func run() error {
+	client := pluginrpc.NewClient(pluginrpc.NewExecRunner("pluginrpc-example-server"))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	if err != nil {
+		return err
+	}
+	stream, err := echoServiceClient.EchoClientStream(context.Background())
+	if err != nil {
+		return err
+	}
+	// Close is a no-op once CloseAndReceive has been called, and otherwise cancels the plugin.
+	defer func() { _ = stream.Close() }()
+	for _, message := range os.Args[1:] {
+		if err := stream.Send(&examplev1.EchoClientStreamRequest{Message: message}); err != nil {
+			if errors.Is(err, io.EOF) {
+				// The plugin has stopped reading requests, and the error will be returned
+				// from CloseAndReceive.
+				break
+			}
+			return err
+		}
+	}
+	response, err := stream.CloseAndReceive()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write([]byte(strings.Join(response.GetMessages(), "\n") + "\n"))
+	return err
+}
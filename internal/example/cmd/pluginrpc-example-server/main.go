@@ -17,6 +17,7 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
 
 	"github.com/bufbuild/pluginrpc-go"
 	examplev1 "github.com/bufbuild/pluginrpc-go/internal/example/gen/buf/pluginrpc/example/v1"
@@ -52,6 +53,36 @@ func (echoServiceHandler) EchoList(context.Context, *examplev1.EchoListRequest)
 	return &examplev1.EchoListResponse{List: []string{"foo", "bar"}}, nil
 }
 
+func (echoServiceHandler) EchoServerStream(
+	_ context.Context,
+	request *examplev1.EchoServerStreamRequest,
+	stream *pluginrpc.ServerStream[examplev1.EchoServerStreamResponse],
+) error {
+	for _, message := range request.GetMessages() {
+		if err := stream.Send(&examplev1.EchoServerStreamResponse{Message: message}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (echoServiceHandler) EchoClientStream(
+	_ context.Context,
+	stream *pluginrpc.ClientStream[examplev1.EchoClientStreamRequest],
+) (*examplev1.EchoClientStreamResponse, error) {
+	var messages []string
+	for {
+		request, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return &examplev1.EchoClientStreamResponse{Messages: messages}, nil
+			}
+			return nil, err
+		}
+		messages = append(messages, request.GetMessage())
+	}
+}
+
 func (echoServiceHandler) EchoError(_ context.Context, request *examplev1.EchoErrorRequest) (*examplev1.EchoErrorResponse, error) {
 	return nil, pluginrpc.NewError(pluginrpc.Code(request.GetCode()), errors.New(request.GetMessage()))
 }
@@ -314,6 +314,198 @@ func (x *EchoListResponse) GetList() []string {
 	return nil
 }
 
+// A request to echo each of the given messages back as a stream.
+type EchoServerStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Messages []string `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+}
+
+func (x *EchoServerStreamRequest) Reset() {
+	*x = EchoServerStreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EchoServerStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EchoServerStreamRequest) ProtoMessage() {}
+
+func (x *EchoServerStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EchoServerStreamRequest.ProtoReflect.Descriptor instead.
+func (*EchoServerStreamRequest) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_example_v1_example_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EchoServerStreamRequest) GetMessages() []string {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+// A single echoed message within a stream.
+type EchoServerStreamResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *EchoServerStreamResponse) Reset() {
+	*x = EchoServerStreamResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EchoServerStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EchoServerStreamResponse) ProtoMessage() {}
+
+func (x *EchoServerStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EchoServerStreamResponse.ProtoReflect.Descriptor instead.
+func (*EchoServerStreamResponse) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_example_v1_example_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *EchoServerStreamResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// A single message to echo within a stream.
+type EchoClientStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *EchoClientStreamRequest) Reset() {
+	*x = EchoClientStreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EchoClientStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EchoClientStreamRequest) ProtoMessage() {}
+
+func (x *EchoClientStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EchoClientStreamRequest.ProtoReflect.Descriptor instead.
+func (*EchoClientStreamRequest) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_example_v1_example_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EchoClientStreamRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// A response containing every message that was sent in the stream.
+type EchoClientStreamResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Messages []string `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+}
+
+func (x *EchoClientStreamResponse) Reset() {
+	*x = EchoClientStreamResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EchoClientStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EchoClientStreamResponse) ProtoMessage() {}
+
+func (x *EchoClientStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_example_v1_example_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EchoClientStreamResponse.ProtoReflect.Descriptor instead.
+func (*EchoClientStreamResponse) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_example_v1_example_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *EchoClientStreamResponse) GetMessages() []string {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
 var File_buf_pluginrpc_example_v1_example_proto protoreflect.FileDescriptor
 
 var file_buf_pluginrpc_example_v1_example_proto_rawDesc = []byte{
@@ -340,45 +532,74 @@ var file_buf_pluginrpc_example_v1_example_proto_rawDesc = []byte{
 	0x0a, 0x0f, 0x45, 0x63, 0x68, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
 	0x74, 0x22, 0x26, 0x0a, 0x10, 0x45, 0x63, 0x68, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73,
 	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x18, 0x01, 0x20,
-	0x03, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x32, 0xc2, 0x02, 0x0a, 0x0b, 0x45, 0x63,
-	0x68, 0x6f, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x6a, 0x0a, 0x0b, 0x45, 0x63, 0x68,
-	0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70,
-	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65,
-	0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2d, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75,
-	0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76,
-	0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x64, 0x0a, 0x09, 0x45, 0x63, 0x68, 0x6f, 0x45, 0x72, 0x72,
-	0x6f, 0x72, 0x12, 0x2a, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72,
+	0x03, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x73, 0x74, 0x22, 0x35, 0x0a, 0x17, 0x45, 0x63, 0x68,
+	0x6f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73,
+	0x22, 0x34, 0x0a, 0x18, 0x45, 0x63, 0x68, 0x6f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x33, 0x0a, 0x17, 0x45, 0x63, 0x68, 0x6f, 0x43, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x36, 0x0a, 0x18, 0x45,
+	0x63, 0x68, 0x6f, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x73, 0x32, 0xc1, 0x04, 0x0a, 0x0b, 0x45, 0x63, 0x68, 0x6f, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x12, 0x6a, 0x0a, 0x0b, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x2c, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72,
 	0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63,
-	0x68, 0x6f, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2b,
+	0x68, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x2d, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63,
+	0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x64, 0x0a, 0x09, 0x45, 0x63, 0x68, 0x6f, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x2a, 0x2e, 0x62,
+	0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61,
+	0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x45, 0x72, 0x72, 0x6f,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2b, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70,
+	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x66, 0x0a, 0x08, 0x45, 0x63, 0x68, 0x6f, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x29, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70,
+	0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68,
+	0x6f, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2a, 0x2e, 0x62,
+	0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61,
+	0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x4c, 0x69, 0x73, 0x74,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x03, 0x90, 0x02, 0x01, 0x12, 0x7b, 0x0a,
+	0x10, 0x45, 0x63, 0x68, 0x6f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x12, 0x31, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70,
+	0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68,
+	0x6f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x32, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69,
+	0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x45, 0x63, 0x68, 0x6f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x7b, 0x0a, 0x10, 0x45, 0x63,
+	0x68, 0x6f, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x31,
 	0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65,
-	0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x45, 0x72,
-	0x72, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x61, 0x0a, 0x08, 0x45,
-	0x63, 0x68, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x29, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c,
-	0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e,
-	0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x2a, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72,
-	0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63,
-	0x68, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x89,
-	0x02, 0x0a, 0x1c, 0x63, 0x6f, 0x6d, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69,
-	0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x42,
-	0x0c, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a,
-	0x58, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x75, 0x66, 0x62,
-	0x75, 0x69, 0x6c, 0x64, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2d, 0x67,
-	0x6f, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x65, 0x78, 0x61, 0x6d, 0x70,
-	0x6c, 0x65, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69,
-	0x6e, 0x72, 0x70, 0x63, 0x2f, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2f, 0x76, 0x31, 0x3b,
-	0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x42, 0x50, 0x45, 0xaa,
-	0x02, 0x18, 0x42, 0x75, 0x66, 0x2e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e,
-	0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x56, 0x31, 0xca, 0x02, 0x18, 0x42, 0x75, 0x66,
-	0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x5c, 0x45, 0x78, 0x61, 0x6d, 0x70,
-	0x6c, 0x65, 0x5c, 0x56, 0x31, 0xe2, 0x02, 0x24, 0x42, 0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67,
-	0x69, 0x6e, 0x72, 0x70, 0x63, 0x5c, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5c, 0x56, 0x31,
-	0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x1b, 0x42,
-	0x75, 0x66, 0x3a, 0x3a, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x3a, 0x3a, 0x45,
-	0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x3a, 0x3a, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x43, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x32, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70,
+	0x63, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x63, 0x68,
+	0x6f, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x42, 0x89, 0x02, 0x0a, 0x1c, 0x63, 0x6f, 0x6d, 0x2e,
+	0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x65, 0x78,
+	0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x42, 0x0c, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c,
+	0x65, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x58, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x70, 0x6c,
+	0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2d, 0x67, 0x6f, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x2f, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2f, 0x67, 0x65, 0x6e, 0x2f,
+	0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2f, 0x65, 0x78,
+	0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2f, 0x76, 0x31, 0x3b, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+	0x76, 0x31, 0xa2, 0x02, 0x03, 0x42, 0x50, 0x45, 0xaa, 0x02, 0x18, 0x42, 0x75, 0x66, 0x2e, 0x50,
+	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+	0x2e, 0x56, 0x31, 0xca, 0x02, 0x18, 0x42, 0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e,
+	0x72, 0x70, 0x63, 0x5c, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5c, 0x56, 0x31, 0xe2, 0x02,
+	0x24, 0x42, 0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x5c, 0x45,
+	0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x1b, 0x42, 0x75, 0x66, 0x3a, 0x3a, 0x50, 0x6c, 0x75,
+	0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x3a, 0x3a, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x3a,
+	0x3a, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -393,29 +614,37 @@ func file_buf_pluginrpc_example_v1_example_proto_rawDescGZIP() []byte {
 	return file_buf_pluginrpc_example_v1_example_proto_rawDescData
 }
 
-var file_buf_pluginrpc_example_v1_example_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_buf_pluginrpc_example_v1_example_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_buf_pluginrpc_example_v1_example_proto_goTypes = []any{
-	(*EchoRequestRequest)(nil),  // 0: buf.pluginrpc.example.v1.EchoRequestRequest
-	(*EchoRequestResponse)(nil), // 1: buf.pluginrpc.example.v1.EchoRequestResponse
-	(*EchoErrorRequest)(nil),    // 2: buf.pluginrpc.example.v1.EchoErrorRequest
-	(*EchoErrorResponse)(nil),   // 3: buf.pluginrpc.example.v1.EchoErrorResponse
-	(*EchoListRequest)(nil),     // 4: buf.pluginrpc.example.v1.EchoListRequest
-	(*EchoListResponse)(nil),    // 5: buf.pluginrpc.example.v1.EchoListResponse
-	(v1beta1.Code)(0),           // 6: buf.pluginrpc.v1beta1.Code
+	(*EchoRequestRequest)(nil),       // 0: buf.pluginrpc.example.v1.EchoRequestRequest
+	(*EchoRequestResponse)(nil),      // 1: buf.pluginrpc.example.v1.EchoRequestResponse
+	(*EchoErrorRequest)(nil),         // 2: buf.pluginrpc.example.v1.EchoErrorRequest
+	(*EchoErrorResponse)(nil),        // 3: buf.pluginrpc.example.v1.EchoErrorResponse
+	(*EchoListRequest)(nil),          // 4: buf.pluginrpc.example.v1.EchoListRequest
+	(*EchoListResponse)(nil),         // 5: buf.pluginrpc.example.v1.EchoListResponse
+	(*EchoServerStreamRequest)(nil),  // 6: buf.pluginrpc.example.v1.EchoServerStreamRequest
+	(*EchoServerStreamResponse)(nil), // 7: buf.pluginrpc.example.v1.EchoServerStreamResponse
+	(*EchoClientStreamRequest)(nil),  // 8: buf.pluginrpc.example.v1.EchoClientStreamRequest
+	(*EchoClientStreamResponse)(nil), // 9: buf.pluginrpc.example.v1.EchoClientStreamResponse
+	(v1beta1.Code)(0),                // 10: buf.pluginrpc.v1beta1.Code
 }
 var file_buf_pluginrpc_example_v1_example_proto_depIdxs = []int32{
-	6, // 0: buf.pluginrpc.example.v1.EchoErrorRequest.code:type_name -> buf.pluginrpc.v1beta1.Code
-	0, // 1: buf.pluginrpc.example.v1.EchoService.EchoRequest:input_type -> buf.pluginrpc.example.v1.EchoRequestRequest
-	2, // 2: buf.pluginrpc.example.v1.EchoService.EchoError:input_type -> buf.pluginrpc.example.v1.EchoErrorRequest
-	4, // 3: buf.pluginrpc.example.v1.EchoService.EchoList:input_type -> buf.pluginrpc.example.v1.EchoListRequest
-	1, // 4: buf.pluginrpc.example.v1.EchoService.EchoRequest:output_type -> buf.pluginrpc.example.v1.EchoRequestResponse
-	3, // 5: buf.pluginrpc.example.v1.EchoService.EchoError:output_type -> buf.pluginrpc.example.v1.EchoErrorResponse
-	5, // 6: buf.pluginrpc.example.v1.EchoService.EchoList:output_type -> buf.pluginrpc.example.v1.EchoListResponse
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	10, // 0: buf.pluginrpc.example.v1.EchoErrorRequest.code:type_name -> buf.pluginrpc.v1beta1.Code
+	0,  // 1: buf.pluginrpc.example.v1.EchoService.EchoRequest:input_type -> buf.pluginrpc.example.v1.EchoRequestRequest
+	2,  // 2: buf.pluginrpc.example.v1.EchoService.EchoError:input_type -> buf.pluginrpc.example.v1.EchoErrorRequest
+	4,  // 3: buf.pluginrpc.example.v1.EchoService.EchoList:input_type -> buf.pluginrpc.example.v1.EchoListRequest
+	6,  // 4: buf.pluginrpc.example.v1.EchoService.EchoServerStream:input_type -> buf.pluginrpc.example.v1.EchoServerStreamRequest
+	8,  // 5: buf.pluginrpc.example.v1.EchoService.EchoClientStream:input_type -> buf.pluginrpc.example.v1.EchoClientStreamRequest
+	1,  // 6: buf.pluginrpc.example.v1.EchoService.EchoRequest:output_type -> buf.pluginrpc.example.v1.EchoRequestResponse
+	3,  // 7: buf.pluginrpc.example.v1.EchoService.EchoError:output_type -> buf.pluginrpc.example.v1.EchoErrorResponse
+	5,  // 8: buf.pluginrpc.example.v1.EchoService.EchoList:output_type -> buf.pluginrpc.example.v1.EchoListResponse
+	7,  // 9: buf.pluginrpc.example.v1.EchoService.EchoServerStream:output_type -> buf.pluginrpc.example.v1.EchoServerStreamResponse
+	9,  // 10: buf.pluginrpc.example.v1.EchoService.EchoClientStream:output_type -> buf.pluginrpc.example.v1.EchoClientStreamResponse
+	6,  // [6:11] is the sub-list for method output_type
+	1,  // [1:6] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_buf_pluginrpc_example_v1_example_proto_init() }
@@ -496,6 +725,54 @@ func file_buf_pluginrpc_example_v1_example_proto_init() {
 				return nil
 			}
 		}
+		file_buf_pluginrpc_example_v1_example_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*EchoServerStreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_example_v1_example_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*EchoServerStreamResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_example_v1_example_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*EchoClientStreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_example_v1_example_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*EchoClientStreamResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -503,7 +780,7 @@ func file_buf_pluginrpc_example_v1_example_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_buf_pluginrpc_example_v1_example_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -39,34 +39,117 @@ const (
 	EchoServiceEchoErrorPath = "/buf.pluginrpc.example.v1.EchoService/EchoError"
 	// EchoServiceEchoListPath is the path of the EchoService's EchoList RPC.
 	EchoServiceEchoListPath = "/buf.pluginrpc.example.v1.EchoService/EchoList"
+	// EchoServiceEchoServerStreamPath is the path of the EchoService's EchoServerStream RPC.
+	EchoServiceEchoServerStreamPath = "/buf.pluginrpc.example.v1.EchoService/EchoServerStream"
+	// EchoServiceEchoClientStreamPath is the path of the EchoService's EchoClientStream RPC.
+	EchoServiceEchoClientStreamPath = "/buf.pluginrpc.example.v1.EchoService/EchoClientStream"
+)
+
+var (
+	echoServiceServiceDescriptor                = v1.File_buf_pluginrpc_example_v1_example_proto.Services().ByName("EchoService")
+	echoServiceEchoRequestMethodDescriptor      = echoServiceServiceDescriptor.Methods().ByName("EchoRequest")
+	echoServiceEchoErrorMethodDescriptor        = echoServiceServiceDescriptor.Methods().ByName("EchoError")
+	echoServiceEchoListMethodDescriptor         = echoServiceServiceDescriptor.Methods().ByName("EchoList")
+	echoServiceEchoServerStreamMethodDescriptor = echoServiceServiceDescriptor.Methods().ByName("EchoServerStream")
+	echoServiceEchoClientStreamMethodDescriptor = echoServiceServiceDescriptor.Methods().ByName("EchoClientStream")
 )
 
 // EchoServiceSpecBuilder builds a Spec for the buf.pluginrpc.example.v1.EchoService service.
 type EchoServiceSpecBuilder struct {
-	EchoRequest []pluginrpc_go.ProcedureOption
-	EchoError   []pluginrpc_go.ProcedureOption
-	EchoList    []pluginrpc_go.ProcedureOption
+	EchoRequest      []pluginrpc_go.ProcedureOption
+	EchoError        []pluginrpc_go.ProcedureOption
+	EchoList         []pluginrpc_go.ProcedureOption
+	EchoServerStream []pluginrpc_go.ProcedureOption
+	EchoClientStream []pluginrpc_go.ProcedureOption
 }
 
 // Build builds a Spec for the buf.pluginrpc.example.v1.EchoService service.
-func (s EchoServiceSpecBuilder) Build() (pluginrpc_go.Spec, error) {
-	procedures := make([]pluginrpc_go.Procedure, 0, 3)
-	procedure, err := pluginrpc_go.NewProcedure(EchoServiceEchoRequestPath, s.EchoRequest...)
+//
+// The description of the Spec defaults to the comments of the service.
+func (s EchoServiceSpecBuilder) Build(options ...pluginrpc_go.SpecOption) (pluginrpc_go.Spec, error) {
+	procedures := make([]pluginrpc_go.Procedure, 0, 5)
+	procedure, err := pluginrpc_go.NewProcedure(
+		EchoServiceEchoRequestPath,
+		append(
+			[]pluginrpc_go.ProcedureOption{
+				pluginrpc_go.ProcedureWithMethodDescriptor(echoServiceEchoRequestMethodDescriptor),
+				pluginrpc_go.ProcedureWithDescription("Echo the request back."),
+			},
+			s.EchoRequest...,
+		)...,
+	)
 	if err != nil {
 		return nil, err
 	}
 	procedures = append(procedures, procedure)
-	procedure, err = pluginrpc_go.NewProcedure(EchoServiceEchoErrorPath, s.EchoError...)
+	procedure, err = pluginrpc_go.NewProcedure(
+		EchoServiceEchoErrorPath,
+		append(
+			[]pluginrpc_go.ProcedureOption{
+				pluginrpc_go.ProcedureWithMethodDescriptor(echoServiceEchoErrorMethodDescriptor),
+				pluginrpc_go.ProcedureWithDescription("Echo the error specified back as an error."),
+			},
+			s.EchoError...,
+		)...,
+	)
 	if err != nil {
 		return nil, err
 	}
 	procedures = append(procedures, procedure)
-	procedure, err = pluginrpc_go.NewProcedure(EchoServiceEchoListPath, s.EchoList...)
+	procedure, err = pluginrpc_go.NewProcedure(
+		EchoServiceEchoListPath,
+		append(
+			[]pluginrpc_go.ProcedureOption{
+				pluginrpc_go.ProcedureWithMethodDescriptor(echoServiceEchoListMethodDescriptor),
+				pluginrpc_go.ProcedureWithDescription("Echo a static list back given an empty request.\n\nThe list is always:\n\n  - foo\n  - bar"),
+				pluginrpc_go.ProcedureWithIdempotent(),
+			},
+			s.EchoList...,
+		)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	procedures = append(procedures, procedure)
+	procedure, err = pluginrpc_go.NewProcedure(
+		EchoServiceEchoServerStreamPath,
+		append(
+			[]pluginrpc_go.ProcedureOption{
+				pluginrpc_go.ProcedureWithMethodDescriptor(echoServiceEchoServerStreamMethodDescriptor),
+				pluginrpc_go.ProcedureWithDescription("Echo each message in the request back as a separate response."),
+				pluginrpc_go.ProcedureWithStreamType(pluginrpc_go.StreamTypeServer),
+			},
+			s.EchoServerStream...,
+		)...,
+	)
 	if err != nil {
 		return nil, err
 	}
 	procedures = append(procedures, procedure)
-	return pluginrpc_go.NewSpec(procedures)
+	procedure, err = pluginrpc_go.NewProcedure(
+		EchoServiceEchoClientStreamPath,
+		append(
+			[]pluginrpc_go.ProcedureOption{
+				pluginrpc_go.ProcedureWithMethodDescriptor(echoServiceEchoClientStreamMethodDescriptor),
+				pluginrpc_go.ProcedureWithDescription("Echo each message sent in the stream back as a list."),
+				pluginrpc_go.ProcedureWithStreamType(pluginrpc_go.StreamTypeClient),
+			},
+			s.EchoClientStream...,
+		)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	procedures = append(procedures, procedure)
+	return pluginrpc_go.NewSpec(
+		procedures,
+		append(
+			[]pluginrpc_go.SpecOption{
+				pluginrpc_go.SpecWithDescription("The service that defines echo operations."),
+			},
+			options...,
+		)...,
+	)
 }
 
 // EchoServiceClient is a client for the buf.pluginrpc.example.v1.EchoService service.
@@ -75,8 +158,17 @@ type EchoServiceClient interface {
 	EchoRequest(context.Context, *v1.EchoRequestRequest, ...pluginrpc_go.CallOption) (*v1.EchoRequestResponse, error)
 	// Echo the error specified back as an error.
 	EchoError(context.Context, *v1.EchoErrorRequest, ...pluginrpc_go.CallOption) (*v1.EchoErrorResponse, error)
-	// Echo a static list ["foo", "bar"] back given an empty request.
+	// Echo a static list back given an empty request.
+	//
+	// The list is always:
+	//
+	//   - foo
+	//   - bar
 	EchoList(context.Context, *v1.EchoListRequest, ...pluginrpc_go.CallOption) (*v1.EchoListResponse, error)
+	// Echo each message in the request back as a separate response.
+	EchoServerStream(context.Context, *v1.EchoServerStreamRequest, ...pluginrpc_go.CallOption) (*pluginrpc_go.ServerStreamForClient[v1.EchoServerStreamResponse], error)
+	// Echo each message sent in the stream back as a list.
+	EchoClientStream(context.Context, ...pluginrpc_go.CallOption) (*pluginrpc_go.ClientStreamForClient[v1.EchoClientStreamRequest, v1.EchoClientStreamResponse], error)
 }
 
 // NewEchoServiceClient constructs a client for the buf.pluginrpc.example.v1.EchoService service.
@@ -92,8 +184,17 @@ type EchoServiceHandler interface {
 	EchoRequest(context.Context, *v1.EchoRequestRequest) (*v1.EchoRequestResponse, error)
 	// Echo the error specified back as an error.
 	EchoError(context.Context, *v1.EchoErrorRequest) (*v1.EchoErrorResponse, error)
-	// Echo a static list ["foo", "bar"] back given an empty request.
+	// Echo a static list back given an empty request.
+	//
+	// The list is always:
+	//
+	//   - foo
+	//   - bar
 	EchoList(context.Context, *v1.EchoListRequest) (*v1.EchoListResponse, error)
+	// Echo each message in the request back as a separate response.
+	EchoServerStream(context.Context, *v1.EchoServerStreamRequest, *pluginrpc_go.ServerStream[v1.EchoServerStreamResponse]) error
+	// Echo each message sent in the stream back as a list.
+	EchoClientStream(context.Context, *pluginrpc_go.ClientStream[v1.EchoClientStreamRequest]) (*v1.EchoClientStreamResponse, error)
 }
 
 // EchoServiceServer serves the buf.pluginrpc.example.v1.EchoService service.
@@ -102,8 +203,17 @@ type EchoServiceServer interface {
 	EchoRequest(context.Context, pluginrpc_go.Env) error
 	// Echo the error specified back as an error.
 	EchoError(context.Context, pluginrpc_go.Env) error
-	// Echo a static list ["foo", "bar"] back given an empty request.
+	// Echo a static list back given an empty request.
+	//
+	// The list is always:
+	//
+	//   - foo
+	//   - bar
 	EchoList(context.Context, pluginrpc_go.Env) error
+	// Echo each message in the request back as a separate response.
+	EchoServerStream(context.Context, pluginrpc_go.Env) error
+	// Echo each message sent in the stream back as a list.
+	EchoClientStream(context.Context, pluginrpc_go.Env) error
 }
 
 // NewEchoServiceServer constructs a server for the buf.pluginrpc.example.v1.EchoService service.
@@ -120,6 +230,8 @@ func RegisterEchoServiceServer(serverRegistrar pluginrpc_go.ServerRegistrar, ech
 	serverRegistrar.Register(EchoServiceEchoRequestPath, echoServiceServer.EchoRequest)
 	serverRegistrar.Register(EchoServiceEchoErrorPath, echoServiceServer.EchoError)
 	serverRegistrar.Register(EchoServiceEchoListPath, echoServiceServer.EchoList)
+	serverRegistrar.Register(EchoServiceEchoServerStreamPath, echoServiceServer.EchoServerStream)
+	serverRegistrar.Register(EchoServiceEchoClientStreamPath, echoServiceServer.EchoClientStream)
 }
 
 // *** PRIVATE ***
@@ -133,6 +245,9 @@ type echoServiceClient struct {
 func (c *echoServiceClient) EchoRequest(ctx context.Context, req *v1.EchoRequestRequest, opts ...pluginrpc_go.CallOption) (*v1.EchoRequestResponse, error) {
 	res := &v1.EchoRequestResponse{}
 	if err := c.client.Call(ctx, EchoServiceEchoRequestPath, req, res, opts...); err != nil {
+		if pluginrpc_go.HasPartialResponse(err) {
+			return res, err
+		}
 		return nil, err
 	}
 	return res, nil
@@ -142,6 +257,9 @@ func (c *echoServiceClient) EchoRequest(ctx context.Context, req *v1.EchoRequest
 func (c *echoServiceClient) EchoError(ctx context.Context, req *v1.EchoErrorRequest, opts ...pluginrpc_go.CallOption) (*v1.EchoErrorResponse, error) {
 	res := &v1.EchoErrorResponse{}
 	if err := c.client.Call(ctx, EchoServiceEchoErrorPath, req, res, opts...); err != nil {
+		if pluginrpc_go.HasPartialResponse(err) {
+			return res, err
+		}
 		return nil, err
 	}
 	return res, nil
@@ -151,11 +269,32 @@ func (c *echoServiceClient) EchoError(ctx context.Context, req *v1.EchoErrorRequ
 func (c *echoServiceClient) EchoList(ctx context.Context, req *v1.EchoListRequest, opts ...pluginrpc_go.CallOption) (*v1.EchoListResponse, error) {
 	res := &v1.EchoListResponse{}
 	if err := c.client.Call(ctx, EchoServiceEchoListPath, req, res, opts...); err != nil {
+		if pluginrpc_go.HasPartialResponse(err) {
+			return res, err
+		}
 		return nil, err
 	}
 	return res, nil
 }
 
+// EchoServerStream calls buf.pluginrpc.example.v1.EchoService.EchoServerStream.
+func (c *echoServiceClient) EchoServerStream(ctx context.Context, req *v1.EchoServerStreamRequest, opts ...pluginrpc_go.CallOption) (*pluginrpc_go.ServerStreamForClient[v1.EchoServerStreamResponse], error) {
+	responseStream, err := c.client.CallServerStream(ctx, EchoServiceEchoServerStreamPath, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return pluginrpc_go.NewServerStreamForClient[v1.EchoServerStreamResponse](responseStream), nil
+}
+
+// EchoClientStream calls buf.pluginrpc.example.v1.EchoService.EchoClientStream.
+func (c *echoServiceClient) EchoClientStream(ctx context.Context, opts ...pluginrpc_go.CallOption) (*pluginrpc_go.ClientStreamForClient[v1.EchoClientStreamRequest, v1.EchoClientStreamResponse], error) {
+	requestStream, err := c.client.CallClientStream(ctx, EchoServiceEchoClientStreamPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return pluginrpc_go.NewClientStreamForClient[v1.EchoClientStreamRequest, v1.EchoClientStreamResponse](requestStream), nil
+}
+
 // echoServiceServer implements EchoServiceServer.
 type echoServiceServer struct {
 	handler            pluginrpc_go.Handler
@@ -209,3 +348,30 @@ func (c *echoServiceServer) EchoList(ctx context.Context, env pluginrpc_go.Env)
 		},
 	)
 }
+
+// EchoServerStream calls buf.pluginrpc.example.v1.EchoService.EchoServerStream.
+func (c *echoServiceServer) EchoServerStream(ctx context.Context, env pluginrpc_go.Env) error {
+	return c.handler.HandleServerStream(
+		ctx,
+		env,
+		&v1.EchoServerStreamRequest{},
+		func(ctx context.Context, anyReq any, send func(any) error) error {
+			req, ok := anyReq.(*v1.EchoServerStreamRequest)
+			if !ok {
+				return fmt.Errorf("could not cast %T to a *v1.EchoServerStreamRequest", anyReq)
+			}
+			return c.echoServiceHandler.EchoServerStream(ctx, req, pluginrpc_go.NewServerStream[v1.EchoServerStreamResponse](send))
+		},
+	)
+}
+
+// EchoClientStream calls buf.pluginrpc.example.v1.EchoService.EchoClientStream.
+func (c *echoServiceServer) EchoClientStream(ctx context.Context, env pluginrpc_go.Env) error {
+	return c.handler.HandleClientStream(
+		ctx,
+		env,
+		func(ctx context.Context, receive func(any) error) (any, error) {
+			return c.echoServiceHandler.EchoClientStream(ctx, pluginrpc_go.NewClientStream[v1.EchoClientStreamRequest](receive))
+		},
+	)
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: buf/pluginrpc/error/v1/error.proto
+
+package errorv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Fields that extend buf.pluginrpc.v1beta1.Error.
+//
+// These fields are encoded as unknown fields of buf.pluginrpc.v1beta1.Error, so the
+// field numbers must never conflict with the fields of buf.pluginrpc.v1beta1.Error.
+// As unknown fields are only preserved in the binary format, these fields are only sent
+// when the binary format is used. Older clients will ignore these fields.
+type ErrorExtension struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Typed details about the error.
+	Details []*anypb.Any `protobuf:"bytes,100,rep,name=details,proto3" json:"details,omitempty"`
+}
+
+func (x *ErrorExtension) Reset() {
+	*x = ErrorExtension{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_error_v1_error_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ErrorExtension) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorExtension) ProtoMessage() {}
+
+func (x *ErrorExtension) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_error_v1_error_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorExtension.ProtoReflect.Descriptor instead.
+func (*ErrorExtension) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_error_v1_error_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ErrorExtension) GetDetails() []*anypb.Any {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+var File_buf_pluginrpc_error_v1_error_proto protoreflect.FileDescriptor
+
+var file_buf_pluginrpc_error_v1_error_proto_rawDesc = []byte{
+	0x0a, 0x22, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2f,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x2f, 0x76, 0x31, 0x2f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x16, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e,
+	0x72, 0x70, 0x63, 0x2e, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x1a, 0x19, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x61, 0x6e,
+	0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x40, 0x0a, 0x0e, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2e, 0x0a, 0x07, 0x64, 0x65, 0x74,
+	0x61, 0x69, 0x6c, 0x73, 0x18, 0x64, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79,
+	0x52, 0x07, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x42, 0xf1, 0x01, 0x0a, 0x1a, 0x63, 0x6f,
+	0x6d, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x42, 0x0a, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x50,
+	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x4c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x70, 0x6c, 0x75, 0x67,
+	0x69, 0x6e, 0x72, 0x70, 0x63, 0x2d, 0x67, 0x6f, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61,
+	0x6c, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e,
+	0x72, 0x70, 0x63, 0x2f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2f, 0x76, 0x31, 0x3b, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x42, 0x50, 0x45, 0xaa, 0x02, 0x16, 0x42, 0x75, 0x66,
+	0x2e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x2e, 0x56, 0x31, 0xca, 0x02, 0x16, 0x42, 0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e,
+	0x72, 0x70, 0x63, 0x5c, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x5c, 0x56, 0x31, 0xe2, 0x02, 0x22, 0x42,
+	0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x5c, 0x45, 0x72, 0x72,
+	0x6f, 0x72, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
+	0x61, 0xea, 0x02, 0x19, 0x42, 0x75, 0x66, 0x3a, 0x3a, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72,
+	0x70, 0x63, 0x3a, 0x3a, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x3a, 0x3a, 0x56, 0x31, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_buf_pluginrpc_error_v1_error_proto_rawDescOnce sync.Once
+	file_buf_pluginrpc_error_v1_error_proto_rawDescData = file_buf_pluginrpc_error_v1_error_proto_rawDesc
+)
+
+func file_buf_pluginrpc_error_v1_error_proto_rawDescGZIP() []byte {
+	file_buf_pluginrpc_error_v1_error_proto_rawDescOnce.Do(func() {
+		file_buf_pluginrpc_error_v1_error_proto_rawDescData = protoimpl.X.CompressGZIP(file_buf_pluginrpc_error_v1_error_proto_rawDescData)
+	})
+	return file_buf_pluginrpc_error_v1_error_proto_rawDescData
+}
+
+var file_buf_pluginrpc_error_v1_error_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_buf_pluginrpc_error_v1_error_proto_goTypes = []any{
+	(*ErrorExtension)(nil), // 0: buf.pluginrpc.error.v1.ErrorExtension
+	(*anypb.Any)(nil),      // 1: google.protobuf.Any
+}
+var file_buf_pluginrpc_error_v1_error_proto_depIdxs = []int32{
+	1, // 0: buf.pluginrpc.error.v1.ErrorExtension.details:type_name -> google.protobuf.Any
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_buf_pluginrpc_error_v1_error_proto_init() }
+func file_buf_pluginrpc_error_v1_error_proto_init() {
+	if File_buf_pluginrpc_error_v1_error_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_buf_pluginrpc_error_v1_error_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ErrorExtension); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_buf_pluginrpc_error_v1_error_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_buf_pluginrpc_error_v1_error_proto_goTypes,
+		DependencyIndexes: file_buf_pluginrpc_error_v1_error_proto_depIdxs,
+		MessageInfos:      file_buf_pluginrpc_error_v1_error_proto_msgTypes,
+	}.Build()
+	File_buf_pluginrpc_error_v1_error_proto = out.File
+	file_buf_pluginrpc_error_v1_error_proto_rawDesc = nil
+	file_buf_pluginrpc_error_v1_error_proto_goTypes = nil
+	file_buf_pluginrpc_error_v1_error_proto_depIdxs = nil
+}
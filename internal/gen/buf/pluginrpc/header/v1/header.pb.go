@@ -0,0 +1,255 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: buf/pluginrpc/header/v1/header.proto
+
+package headerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A set of key-value metadata sent in a header frame.
+//
+// Clients that support headers send a header frame before any requests. Plugins
+// only send a header frame before any responses if the client sent a header frame.
+type Header struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The fields of the header.
+	Fields []*Header_Field `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty"`
+}
+
+func (x *Header) Reset() {
+	*x = Header{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_header_v1_header_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Header) ProtoMessage() {}
+
+func (x *Header) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_header_v1_header_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Header.ProtoReflect.Descriptor instead.
+func (*Header) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_header_v1_header_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Header) GetFields() []*Header_Field {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// A single key and its values.
+type Header_Field struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The key of the field.
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The values of the field.
+	Values []string `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *Header_Field) Reset() {
+	*x = Header_Field{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_header_v1_header_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Header_Field) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Header_Field) ProtoMessage() {}
+
+func (x *Header_Field) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_header_v1_header_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Header_Field.ProtoReflect.Descriptor instead.
+func (*Header_Field) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_header_v1_header_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *Header_Field) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Header_Field) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+var File_buf_pluginrpc_header_v1_header_proto protoreflect.FileDescriptor
+
+var file_buf_pluginrpc_header_v1_header_proto_rawDesc = []byte{
+	0x0a, 0x24, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2f,
+	0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2f, 0x76, 0x31, 0x2f, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x17, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67,
+	0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x22,
+	0x7a, 0x0a, 0x06, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x3d, 0x0a, 0x06, 0x66, 0x69, 0x65,
+	0x6c, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x62, 0x75, 0x66, 0x2e,
+	0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x2e, 0x76, 0x31, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64,
+	0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x1a, 0x31, 0x0a, 0x05, 0x46, 0x69, 0x65, 0x6c,
+	0x64, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x42, 0xf9, 0x01, 0x0a, 0x1b,
+	0x63, 0x6f, 0x6d, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70,
+	0x63, 0x2e, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x42, 0x0b, 0x48, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x4e, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f,
+	0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2d, 0x67, 0x6f, 0x2f, 0x69, 0x6e, 0x74,
+	0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c,
+	0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2f, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2f, 0x76,
+	0x31, 0x3b, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x42, 0x50, 0x48,
+	0xaa, 0x02, 0x17, 0x42, 0x75, 0x66, 0x2e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63,
+	0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2e, 0x56, 0x31, 0xca, 0x02, 0x17, 0x42, 0x75, 0x66,
+	0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x5c, 0x48, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x5c, 0x56, 0x31, 0xe2, 0x02, 0x23, 0x42, 0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69,
+	0x6e, 0x72, 0x70, 0x63, 0x5c, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x5c, 0x56, 0x31, 0x5c, 0x47,
+	0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x1a, 0x42, 0x75, 0x66,
+	0x3a, 0x3a, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x3a, 0x3a, 0x48, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x3a, 0x3a, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_buf_pluginrpc_header_v1_header_proto_rawDescOnce sync.Once
+	file_buf_pluginrpc_header_v1_header_proto_rawDescData = file_buf_pluginrpc_header_v1_header_proto_rawDesc
+)
+
+func file_buf_pluginrpc_header_v1_header_proto_rawDescGZIP() []byte {
+	file_buf_pluginrpc_header_v1_header_proto_rawDescOnce.Do(func() {
+		file_buf_pluginrpc_header_v1_header_proto_rawDescData = protoimpl.X.CompressGZIP(file_buf_pluginrpc_header_v1_header_proto_rawDescData)
+	})
+	return file_buf_pluginrpc_header_v1_header_proto_rawDescData
+}
+
+var file_buf_pluginrpc_header_v1_header_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_buf_pluginrpc_header_v1_header_proto_goTypes = []any{
+	(*Header)(nil),       // 0: buf.pluginrpc.header.v1.Header
+	(*Header_Field)(nil), // 1: buf.pluginrpc.header.v1.Header.Field
+}
+var file_buf_pluginrpc_header_v1_header_proto_depIdxs = []int32{
+	1, // 0: buf.pluginrpc.header.v1.Header.fields:type_name -> buf.pluginrpc.header.v1.Header.Field
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_buf_pluginrpc_header_v1_header_proto_init() }
+func file_buf_pluginrpc_header_v1_header_proto_init() {
+	if File_buf_pluginrpc_header_v1_header_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_buf_pluginrpc_header_v1_header_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Header); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_header_v1_header_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Header_Field); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_buf_pluginrpc_header_v1_header_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_buf_pluginrpc_header_v1_header_proto_goTypes,
+		DependencyIndexes: file_buf_pluginrpc_header_v1_header_proto_depIdxs,
+		MessageInfos:      file_buf_pluginrpc_header_v1_header_proto_msgTypes,
+	}.Build()
+	File_buf_pluginrpc_header_v1_header_proto = out.File
+	file_buf_pluginrpc_header_v1_header_proto_rawDesc = nil
+	file_buf_pluginrpc_header_v1_header_proto_goTypes = nil
+	file_buf_pluginrpc_header_v1_header_proto_depIdxs = nil
+}
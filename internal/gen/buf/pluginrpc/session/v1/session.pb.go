@@ -0,0 +1,594 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: buf/pluginrpc/session/v1/session.proto
+
+package sessionv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A message sent from a client to a plugin running with `--plugin-serve`.
+//
+// Each SessionRequest either starts a new call, or cancels an existing call.
+type SessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The ID of the call.
+	//
+	// IDs are chosen by the client, and must be unique within a session.
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are assignable to Value:
+	//	*SessionRequest_Start_
+	//	*SessionRequest_Cancel_
+	Value isSessionRequest_Value `protobuf_oneof:"value"`
+}
+
+func (x *SessionRequest) Reset() {
+	*x = SessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionRequest) ProtoMessage() {}
+
+func (x *SessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionRequest.ProtoReflect.Descriptor instead.
+func (*SessionRequest) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_session_v1_session_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SessionRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (m *SessionRequest) GetValue() isSessionRequest_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *SessionRequest) GetStart() *SessionRequest_Start {
+	if x, ok := x.GetValue().(*SessionRequest_Start_); ok {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *SessionRequest) GetCancel() *SessionRequest_Cancel {
+	if x, ok := x.GetValue().(*SessionRequest_Cancel_); ok {
+		return x.Cancel
+	}
+	return nil
+}
+
+type isSessionRequest_Value interface {
+	isSessionRequest_Value()
+}
+
+type SessionRequest_Start_ struct {
+	// Start a new call.
+	Start *SessionRequest_Start `protobuf:"bytes,2,opt,name=start,proto3,oneof"`
+}
+
+type SessionRequest_Cancel_ struct {
+	// Cancel the call with the given ID.
+	Cancel *SessionRequest_Cancel `protobuf:"bytes,3,opt,name=cancel,proto3,oneof"`
+}
+
+func (*SessionRequest_Start_) isSessionRequest_Value() {}
+
+func (*SessionRequest_Cancel_) isSessionRequest_Value() {}
+
+// A message sent from a plugin running with `--plugin-serve` to a client.
+//
+// Each SessionResponse contains either a chunk of output for a call, or
+// signals that the call has completed.
+type SessionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The ID of the call this is a response for.
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are assignable to Value:
+	//	*SessionResponse_Stdout
+	//	*SessionResponse_Stderr
+	//	*SessionResponse_Exit_
+	Value isSessionResponse_Value `protobuf_oneof:"value"`
+}
+
+func (x *SessionResponse) Reset() {
+	*x = SessionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionResponse) ProtoMessage() {}
+
+func (x *SessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionResponse.ProtoReflect.Descriptor instead.
+func (*SessionResponse) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_session_v1_session_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SessionResponse) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (m *SessionResponse) GetValue() isSessionResponse_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *SessionResponse) GetStdout() []byte {
+	if x, ok := x.GetValue().(*SessionResponse_Stdout); ok {
+		return x.Stdout
+	}
+	return nil
+}
+
+func (x *SessionResponse) GetStderr() []byte {
+	if x, ok := x.GetValue().(*SessionResponse_Stderr); ok {
+		return x.Stderr
+	}
+	return nil
+}
+
+func (x *SessionResponse) GetExit() *SessionResponse_Exit {
+	if x, ok := x.GetValue().(*SessionResponse_Exit_); ok {
+		return x.Exit
+	}
+	return nil
+}
+
+type isSessionResponse_Value interface {
+	isSessionResponse_Value()
+}
+
+type SessionResponse_Stdout struct {
+	// A chunk of data written to stdout by the call.
+	Stdout []byte `protobuf:"bytes,2,opt,name=stdout,proto3,oneof"`
+}
+
+type SessionResponse_Stderr struct {
+	// A chunk of data written to stderr by the call.
+	Stderr []byte `protobuf:"bytes,3,opt,name=stderr,proto3,oneof"`
+}
+
+type SessionResponse_Exit_ struct {
+	// The call has completed. No further SessionResponses will be sent for the call.
+	Exit *SessionResponse_Exit `protobuf:"bytes,4,opt,name=exit,proto3,oneof"`
+}
+
+func (*SessionResponse_Stdout) isSessionResponse_Value() {}
+
+func (*SessionResponse_Stderr) isSessionResponse_Value() {}
+
+func (*SessionResponse_Exit_) isSessionResponse_Value() {}
+
+// Start a new call.
+type SessionRequest_Start struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The args to invoke the plugin with.
+	Args []string `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
+	// The entire contents of stdin for the call.
+	Stdin []byte `protobuf:"bytes,2,opt,name=stdin,proto3" json:"stdin,omitempty"`
+	// Environment variables for the call, in the form "key=value".
+	//
+	// These are added to the environment variables of the plugin process.
+	Environ []string `protobuf:"bytes,3,rep,name=environ,proto3" json:"environ,omitempty"`
+}
+
+func (x *SessionRequest_Start) Reset() {
+	*x = SessionRequest_Start{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionRequest_Start) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionRequest_Start) ProtoMessage() {}
+
+func (x *SessionRequest_Start) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionRequest_Start.ProtoReflect.Descriptor instead.
+func (*SessionRequest_Start) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_session_v1_session_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *SessionRequest_Start) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *SessionRequest_Start) GetStdin() []byte {
+	if x != nil {
+		return x.Stdin
+	}
+	return nil
+}
+
+func (x *SessionRequest_Start) GetEnviron() []string {
+	if x != nil {
+		return x.Environ
+	}
+	return nil
+}
+
+// Cancel an existing call.
+type SessionRequest_Cancel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SessionRequest_Cancel) Reset() {
+	*x = SessionRequest_Cancel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionRequest_Cancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionRequest_Cancel) ProtoMessage() {}
+
+func (x *SessionRequest_Cancel) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionRequest_Cancel.ProtoReflect.Descriptor instead.
+func (*SessionRequest_Cancel) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_session_v1_session_proto_rawDescGZIP(), []int{0, 1}
+}
+
+// The call has completed.
+type SessionResponse_Exit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The exit code of the call.
+	//
+	// An exit code of 0 indicates success.
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// The error message of the call, if the exit code is non-zero.
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *SessionResponse_Exit) Reset() {
+	*x = SessionResponse_Exit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionResponse_Exit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionResponse_Exit) ProtoMessage() {}
+
+func (x *SessionResponse_Exit) ProtoReflect() protoreflect.Message {
+	mi := &file_buf_pluginrpc_session_v1_session_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionResponse_Exit.ProtoReflect.Descriptor instead.
+func (*SessionResponse_Exit) Descriptor() ([]byte, []int) {
+	return file_buf_pluginrpc_session_v1_session_proto_rawDescGZIP(), []int{1, 0}
+}
+
+func (x *SessionResponse_Exit) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SessionResponse_Exit) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_buf_pluginrpc_session_v1_session_proto protoreflect.FileDescriptor
+
+var file_buf_pluginrpc_session_v1_session_proto_rawDesc = []byte{
+	0x0a, 0x26, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2f,
+	0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x18, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c,
+	0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e,
+	0x76, 0x31, 0x22, 0x93, 0x02, 0x0a, 0x0e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x46, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69,
+	0x6e, 0x72, 0x70, 0x63, 0x2e, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x53,
+	0x74, 0x61, 0x72, 0x74, 0x48, 0x00, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x49, 0x0a,
+	0x06, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2f, 0x2e,
+	0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x73, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x48, 0x00,
+	0x52, 0x06, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x1a, 0x4b, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x72,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x04, 0x61, 0x72, 0x67, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x64, 0x69, 0x6e, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x74, 0x64, 0x69, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x65,
+	0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x65, 0x6e,
+	0x76, 0x69, 0x72, 0x6f, 0x6e, 0x1a, 0x08, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x42,
+	0x07, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xda, 0x01, 0x0a, 0x0f, 0x53, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x06,
+	0x73, 0x74, 0x64, 0x6f, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x06,
+	0x73, 0x74, 0x64, 0x6f, 0x75, 0x74, 0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72,
+	0x12, 0x44, 0x0a, 0x04, 0x65, 0x78, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2e,
+	0x2e, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x45, 0x78, 0x69, 0x74, 0x48, 0x00,
+	0x52, 0x04, 0x65, 0x78, 0x69, 0x74, 0x1a, 0x34, 0x0a, 0x04, 0x45, 0x78, 0x69, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x42, 0x07, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x42, 0x81, 0x02, 0x0a, 0x1c, 0x63, 0x6f, 0x6d, 0x2e, 0x62, 0x75,
+	0x66, 0x2e, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x73, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x42, 0x0c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x50,
+	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x50, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x70, 0x6c, 0x75, 0x67,
+	0x69, 0x6e, 0x72, 0x70, 0x63, 0x2d, 0x67, 0x6f, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61,
+	0x6c, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e,
+	0x72, 0x70, 0x63, 0x2f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2f, 0x76, 0x31, 0x3b, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x42, 0x50, 0x53, 0xaa, 0x02,
+	0x18, 0x42, 0x75, 0x66, 0x2e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x2e, 0x53,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x56, 0x31, 0xca, 0x02, 0x18, 0x42, 0x75, 0x66, 0x5c,
+	0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x5c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x5c, 0x56, 0x31, 0xe2, 0x02, 0x24, 0x42, 0x75, 0x66, 0x5c, 0x50, 0x6c, 0x75, 0x67, 0x69,
+	0x6e, 0x72, 0x70, 0x63, 0x5c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5c, 0x56, 0x31, 0x5c,
+	0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x1b, 0x42, 0x75,
+	0x66, 0x3a, 0x3a, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x72, 0x70, 0x63, 0x3a, 0x3a, 0x53, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x3a, 0x3a, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_buf_pluginrpc_session_v1_session_proto_rawDescOnce sync.Once
+	file_buf_pluginrpc_session_v1_session_proto_rawDescData = file_buf_pluginrpc_session_v1_session_proto_rawDesc
+)
+
+func file_buf_pluginrpc_session_v1_session_proto_rawDescGZIP() []byte {
+	file_buf_pluginrpc_session_v1_session_proto_rawDescOnce.Do(func() {
+		file_buf_pluginrpc_session_v1_session_proto_rawDescData = protoimpl.X.CompressGZIP(file_buf_pluginrpc_session_v1_session_proto_rawDescData)
+	})
+	return file_buf_pluginrpc_session_v1_session_proto_rawDescData
+}
+
+var file_buf_pluginrpc_session_v1_session_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_buf_pluginrpc_session_v1_session_proto_goTypes = []any{
+	(*SessionRequest)(nil),        // 0: buf.pluginrpc.session.v1.SessionRequest
+	(*SessionResponse)(nil),       // 1: buf.pluginrpc.session.v1.SessionResponse
+	(*SessionRequest_Start)(nil),  // 2: buf.pluginrpc.session.v1.SessionRequest.Start
+	(*SessionRequest_Cancel)(nil), // 3: buf.pluginrpc.session.v1.SessionRequest.Cancel
+	(*SessionResponse_Exit)(nil),  // 4: buf.pluginrpc.session.v1.SessionResponse.Exit
+}
+var file_buf_pluginrpc_session_v1_session_proto_depIdxs = []int32{
+	2, // 0: buf.pluginrpc.session.v1.SessionRequest.start:type_name -> buf.pluginrpc.session.v1.SessionRequest.Start
+	3, // 1: buf.pluginrpc.session.v1.SessionRequest.cancel:type_name -> buf.pluginrpc.session.v1.SessionRequest.Cancel
+	4, // 2: buf.pluginrpc.session.v1.SessionResponse.exit:type_name -> buf.pluginrpc.session.v1.SessionResponse.Exit
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_buf_pluginrpc_session_v1_session_proto_init() }
+func file_buf_pluginrpc_session_v1_session_proto_init() {
+	if File_buf_pluginrpc_session_v1_session_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_buf_pluginrpc_session_v1_session_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*SessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_session_v1_session_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*SessionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_session_v1_session_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*SessionRequest_Start); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_session_v1_session_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*SessionRequest_Cancel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_buf_pluginrpc_session_v1_session_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*SessionResponse_Exit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_buf_pluginrpc_session_v1_session_proto_msgTypes[0].OneofWrappers = []any{
+		(*SessionRequest_Start_)(nil),
+		(*SessionRequest_Cancel_)(nil),
+	}
+	file_buf_pluginrpc_session_v1_session_proto_msgTypes[1].OneofWrappers = []any{
+		(*SessionResponse_Stdout)(nil),
+		(*SessionResponse_Stderr)(nil),
+		(*SessionResponse_Exit_)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_buf_pluginrpc_session_v1_session_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_buf_pluginrpc_session_v1_session_proto_goTypes,
+		DependencyIndexes: file_buf_pluginrpc_session_v1_session_proto_depIdxs,
+		MessageInfos:      file_buf_pluginrpc_session_v1_session_proto_msgTypes,
+	}.Build()
+	File_buf_pluginrpc_session_v1_session_proto = out.File
+	file_buf_pluginrpc_session_v1_session_proto_rawDesc = nil
+	file_buf_pluginrpc_session_v1_session_proto_goTypes = nil
+	file_buf_pluginrpc_session_v1_session_proto_depIdxs = nil
+}
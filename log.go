@@ -0,0 +1,324 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LoggerFromContext returns the *slog.Logger for the call being handled.
+//
+// This is meant to be used within handlers. If the client requested structured logs via
+// ClientWithLogHandler, records are sent to the client, which forwards them to its
+// slog.Handler. Otherwise, records are written as text to stderr, so that logs are
+// readable when the plugin is invoked as a CLI.
+//
+// If the context is not the context of a call being handled, this returns a *slog.Logger
+// that discards all records.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger)
+	if !ok {
+		return slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return logger
+}
+
+// *** PRIVATE ***
+
+const (
+	// headerKeyLogFormat is the key of the request header field containing the format the
+	// client wants log records to be written to stderr in.
+	headerKeyLogFormat = "pluginrpc-log-format"
+	// logFormatJSON is the logFormat for log records written as JSON text sequences.
+	logFormatJSON = "json"
+	// logRecordSeparator is the byte that precedes every structured log record written to
+	// stderr, as per RFC 7464. All other output on stderr is passed through.
+	logRecordSeparator = 0x1e
+	// logAttrKeyProcedure is the key of the attribute clients add to forwarded log records,
+	// containing the path of the Procedure being called.
+	logAttrKeyProcedure = "procedure"
+)
+
+type loggerContextKey struct{}
+
+// withLogger returns a context with a *slog.Logger that writes to stderr in the format
+// requested by the client via the request Header.
+func withLogger(ctx context.Context, stderr io.Writer, requestHeader Header) context.Context {
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	var logHandler slog.Handler
+	if requestHeader.Get(headerKeyLogFormat) == logFormatJSON {
+		// The client decides which records are enabled, so we send everything.
+		logHandler = slog.NewJSONHandler(
+			&logRecordWriter{stderr: stderr},
+			&slog.HandlerOptions{Level: slog.LevelDebug},
+		)
+	} else {
+		logHandler = slog.NewTextHandler(stderr, nil)
+	}
+	return context.WithValue(ctx, loggerContextKey{}, slog.New(logHandler))
+}
+
+// logRecordWriter precedes every write with logRecordSeparator.
+//
+// slog handlers write each record with a single call to Write, so each write is a
+// complete, newline-terminated record.
+type logRecordWriter struct {
+	stderr io.Writer
+}
+
+func (l *logRecordWriter) Write(data []byte) (int, error) {
+	if _, err := l.stderr.Write(append([]byte{logRecordSeparator}, data...)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// logWriter is the stderr of a call made by a client with a slog.Handler.
+//
+// Lines that start with logRecordSeparator are decoded as log records and forwarded to
+// the slog.Handler. All other output is passed through to stderr as-is, so that the stderr
+// of plugins that do not support structured logging is not affected.
+type logWriter struct {
+	ctx        context.Context
+	logHandler slog.Handler
+	stderr     io.Writer
+
+	// atLineStart is true if the next byte written starts a new line.
+	atLineStart bool
+	// inRecord is true if the current line is a log record.
+	inRecord bool
+	// record is the data of the current log record.
+	record []byte
+	lock   sync.Mutex
+}
+
+func newLogWriter(
+	ctx context.Context,
+	logHandler slog.Handler,
+	procedurePath string,
+	stderr io.Writer,
+) *logWriter {
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	return &logWriter{
+		ctx:         ctx,
+		logHandler:  logHandler.WithAttrs([]slog.Attr{slog.String(logAttrKeyProcedure, procedurePath)}),
+		stderr:      stderr,
+		atLineStart: true,
+	}
+}
+
+func (l *logWriter) Write(data []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	size := len(data)
+	for len(data) > 0 {
+		if l.atLineStart {
+			l.atLineStart = false
+			if data[0] == logRecordSeparator {
+				l.inRecord = true
+				data = data[1:]
+				continue
+			}
+		}
+		index := bytes.IndexByte(data, '\n')
+		line := data
+		if index >= 0 {
+			line = data[:index+1]
+		}
+		data = data[len(line):]
+		if l.inRecord {
+			l.record = append(l.record, line...)
+		} else if _, err := l.stderr.Write(line); err != nil {
+			return 0, err
+		}
+		if index >= 0 {
+			l.atLineStart = true
+			if l.inRecord {
+				l.inRecord = false
+				if err := l.handleRecord(); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	return size, nil
+}
+
+// Flush handles any incomplete log record.
+//
+// This should be called once the plugin has exited. As the plugin has exited, there is
+// nothing to report errors to, so errors writing to stderr are ignored.
+func (l *logWriter) Flush() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.inRecord {
+		return
+	}
+	l.inRecord = false
+	_ = l.handleRecord()
+}
+
+// handleRecord decodes the current log record and forwards it to the slog.Handler.
+//
+// If the record cannot be decoded, it is passed through to stderr instead. Errors from
+// the slog.Handler are ignored, as with slog.Logger.
+func (l *logWriter) handleRecord() error {
+	data := l.record
+	l.record = nil
+	record, err := decodeLogRecord(data)
+	if err != nil {
+		_, err := l.stderr.Write(data)
+		return err
+	}
+	if l.logHandler.Enabled(l.ctx, record.Level) {
+		_ = l.logHandler.Handle(l.ctx, record)
+	}
+	return nil
+}
+
+// decodeLogRecord decodes a log record written by slog.JSONHandler.
+func decodeLogRecord(data []byte) (slog.Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := expectJSONDelim(decoder, '{'); err != nil {
+		return slog.Record{}, err
+	}
+	attrs, err := decodeLogAttrs(decoder)
+	if err != nil {
+		return slog.Record{}, err
+	}
+	recordTime := time.Now()
+	level := slog.LevelInfo
+	var message string
+	otherAttrs := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		switch attr.Key {
+		case slog.TimeKey:
+			if parsedTime, err := time.Parse(time.RFC3339Nano, attr.Value.String()); err == nil {
+				recordTime = parsedTime
+			}
+		case slog.LevelKey:
+			if err := level.UnmarshalText([]byte(attr.Value.String())); err != nil {
+				return slog.Record{}, err
+			}
+		case slog.MessageKey:
+			message = attr.Value.String()
+		default:
+			otherAttrs = append(otherAttrs, attr)
+		}
+	}
+	record := slog.NewRecord(recordTime, level, message, 0)
+	record.AddAttrs(otherAttrs...)
+	return record, nil
+}
+
+// decodeLogAttrs decodes the fields of a JSON object as slog.Attrs, preserving their order.
+//
+// The opening delimiter of the object must already have been read.
+func decodeLogAttrs(decoder *json.Decoder) ([]slog.Attr, error) {
+	var attrs []slog.Attr
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected object key, got %v", token)
+		}
+		value, err := decodeLogValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: value})
+	}
+	if err := expectJSONDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+func decodeLogValue(decoder *json.Decoder) (slog.Value, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			attrs, err := decodeLogAttrs(decoder)
+			if err != nil {
+				return slog.Value{}, err
+			}
+			return slog.GroupValue(attrs...), nil
+		case '[':
+			var values []any
+			for decoder.More() {
+				var value any
+				if err := decoder.Decode(&value); err != nil {
+					return slog.Value{}, err
+				}
+				values = append(values, value)
+			}
+			if err := expectJSONDelim(decoder, ']'); err != nil {
+				return slog.Value{}, err
+			}
+			return slog.AnyValue(values), nil
+		default:
+			return slog.Value{}, fmt.Errorf("unexpected delimiter %v", token)
+		}
+	case json.Number:
+		if intValue, err := token.Int64(); err == nil {
+			return slog.Int64Value(intValue), nil
+		}
+		floatValue, err := token.Float64()
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return slog.Float64Value(floatValue), nil
+	case string:
+		return slog.StringValue(token), nil
+	case bool:
+		return slog.BoolValue(token), nil
+	case nil:
+		return slog.AnyValue(nil), nil
+	default:
+		return slog.Value{}, fmt.Errorf("unexpected token %v", token)
+	}
+}
+
+func expectJSONDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return errors.New("malformed log record")
+	}
+	return nil
+}
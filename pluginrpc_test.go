@@ -15,15 +15,40 @@
 package pluginrpc_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
 	"github.com/bufbuild/pluginrpc-go"
 	examplev1 "github.com/bufbuild/pluginrpc-go/internal/example/gen/buf/pluginrpc/example/v1"
 	"github.com/bufbuild/pluginrpc-go/internal/example/gen/buf/pluginrpc/example/v1/examplev1pluginrpc"
+	errorv1 "github.com/bufbuild/pluginrpc-go/internal/gen/buf/pluginrpc/error/v1"
+	"github.com/bufbuild/pluginrpc-go/protovalidate"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestEchoRequest(t *testing.T) {
@@ -98,42 +123,2197 @@ func TestEchoError(t *testing.T) {
 	require.NoError(t, err)
 	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
 	require.NoError(t, err)
+	response, err := echoServiceClient.EchoError(
+		context.Background(),
+		&examplev1.EchoErrorRequest{
+			Code:    pluginrpcv1beta1.Code_CODE_DEADLINE_EXCEEDED,
+			Message: "hello",
+		},
+	)
+	require.Nil(t, response)
+	require.False(t, pluginrpc.HasPartialResponse(err))
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
+	unwrappedErr := pluginrpcError.Unwrap()
+	require.Error(t, unwrappedErr)
+	require.Equal(t, "hello", unwrappedErr.Error())
+}
+
+func TestPartialResponse(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+					return func(ctx context.Context, procedurePath string, request any) (any, error) {
+						response, err := next(ctx, procedurePath, request)
+						if err != nil {
+							return nil, err
+						}
+						// Return the response alongside an error.
+						return response, pluginrpc.NewErrorf(pluginrpc.CodeDataLoss, "partial")
+					}
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoList(context.Background(), nil)
+	require.True(t, pluginrpc.HasPartialResponse(err))
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeDataLoss, pluginrpcError.Code())
+	require.Equal(t, []string{"foo", "bar"}, response.GetList())
+}
+
+func TestErrorDetails(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				func(pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+					return func(context.Context, string, any) (any, error) {
+						errorDetail, err := pluginrpc.NewErrorDetail(wrapperspb.String("detail"))
+						if err != nil {
+							return nil, err
+						}
+						pluginrpcError := pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "invalid")
+						pluginrpcError.AddDetail(errorDetail)
+						return nil, pluginrpcError
+					}
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	stderr := &bytes.Buffer{}
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server, pluginrpc.ClientWithStderr(stderr)))
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+	require.Empty(t, stderr.String())
+	details := pluginrpcError.Details()
+	require.Len(t, details, 1)
+	require.Equal(t, "google.protobuf.StringValue", details[0].Type())
+	value, err := details[0].Value()
+	require.NoError(t, err)
+	require.True(t, proto.Equal(wrapperspb.String("detail"), value))
+	stringValue := &wrapperspb.StringValue{}
+	require.NoError(t, details[0].UnmarshalTo(stringValue))
+	require.Equal(t, "detail", stringValue.GetValue())
+
+	// Details are not sent in the JSON format, and the plugin warns that they were dropped.
+	client := pluginrpc.NewClient(
+		newBaseProtocolRunner(pluginrpc.NewServerRunner(server)),
+		pluginrpc.ClientWithStderr(stderr),
+	)
+	echoServiceClient, err = examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+	require.Empty(t, pluginrpcError.Details())
+	require.Contains(t, stderr.String(), "dropped error details")
+	require.Contains(t, stderr.String(), "google.protobuf.StringValue")
+
+	// The same applies when the plugin is invoked on the command line.
+	stdout := &bytes.Buffer{}
+	stderr.Reset()
+	err = pluginrpc.NewServerRunner(server).Run(
+		context.Background(),
+		pluginrpc.Env{
+			Args:   []string{examplev1pluginrpc.EchoServiceEchoRequestPath, "--message=foo"},
+			Stdout: stdout,
+			Stderr: stderr,
+		},
+	)
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "invalid")
+	require.Contains(t, stderr.String(), "google.protobuf.StringValue")
+
+	// The details are encoded as unknown fields of pluginrpcv1beta1.Error, so the field
+	// numbers must not be used upstream.
+	errorFields := (&pluginrpcv1beta1.Error{}).ProtoReflect().Descriptor().Fields()
+	errorExtensionFields := (&errorv1.ErrorExtension{}).ProtoReflect().Descriptor().Fields()
+	for i := 0; i < errorExtensionFields.Len(); i++ {
+		require.Nil(t, errorFields.ByNumber(errorExtensionFields.Get(i).Number()))
+	}
+}
+
+func TestEchoServerStream(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "bar", "baz"},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, stream.Close()) })
+	var messages []string
+	for {
+		response, err := stream.Receive()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		messages = append(messages, response.GetMessage())
+	}
+	require.Equal(t, []string{"foo", "bar", "baz"}, messages)
+}
+
+func TestEchoServerStreamPartialResponse(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(
+		pluginrpc.NewClient(newPartialServerStreamRunner(pluginrpc.NewServerRunner(server))),
+	)
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoServerStream(context.Background(), &examplev1.EchoServerStreamRequest{})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, stream.Close()) })
+	response, err := stream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	response, err = stream.Receive()
+	require.True(t, pluginrpc.HasPartialResponse(err))
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInternal, pluginrpcError.Code())
+	require.NotNil(t, response)
+	require.Equal(t, "bar", response.GetMessage())
+}
+
+func TestProcedureStreamType(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	spec, err := newClient(server).Spec(context.Background())
+	require.NoError(t, err)
+	for path, streamType := range map[string]pluginrpc.StreamType{
+		examplev1pluginrpc.EchoServiceEchoRequestPath:      pluginrpc.StreamTypeUnary,
+		examplev1pluginrpc.EchoServiceEchoServerStreamPath: pluginrpc.StreamTypeServer,
+		examplev1pluginrpc.EchoServiceEchoClientStreamPath: pluginrpc.StreamTypeClient,
+	} {
+		procedure := spec.ProcedureForPath(path)
+		require.NotNil(t, procedure, path)
+		require.Equal(t, streamType, procedure.StreamType(), path)
+	}
+	_, err = pluginrpc.NewProcedure("/foo.Bar/Baz", pluginrpc.ProcedureWithStreamType(pluginrpc.StreamType(3)))
+	require.Error(t, err)
+}
+
+func TestEchoServerStreamError(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "", "bar"},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, stream.Close()) })
+	response, err := stream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	_, err = stream.Receive()
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+	_, err = stream.Receive()
+	require.ErrorAs(t, err, &pluginrpcError)
+}
+
+func TestEchoServerStreamCloseEarly(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "bar", "baz"},
+		},
+	)
+	require.NoError(t, err)
+	response, err := stream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	require.NoError(t, stream.Close())
+}
+
+func TestEchoClientStream(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	for _, message := range []string{"foo", "bar", "baz"} {
+		require.NoError(t, stream.Send(&examplev1.EchoClientStreamRequest{Message: message}))
+	}
+	response, err := stream.CloseAndReceive()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo", "bar", "baz"}, response.GetMessages())
+}
+
+func TestEchoClientStreamClose(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	// The plugin runs until it is cancelled, so Close only returns once it cancelled the plugin.
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(
+		pluginrpc.NewClient(newBlockingRunner(pluginrpc.NewServerRunner(server))),
+	)
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	closed := make(chan error, 1)
+	go func() { closed <- stream.Close() }()
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close did not cancel the plugin")
+	}
+	require.NoError(t, stream.Close())
+	_, err = stream.CloseAndReceive()
+	require.Error(t, err)
+
+	// Close is a no-op after CloseAndReceive.
+	echoServiceClient, err = examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err = echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&examplev1.EchoClientStreamRequest{Message: "foo"}))
+	response, err := stream.CloseAndReceive()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, response.GetMessages())
+	require.NoError(t, stream.Close())
+}
+
+func TestEchoClientStreamEmpty(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	response, err := stream.CloseAndReceive()
+	require.NoError(t, err)
+	require.Empty(t, response.GetMessages())
+}
+
+func TestEchoClientStreamError(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	stream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&examplev1.EchoClientStreamRequest{Message: "foo"}))
+	require.NoError(t, stream.Send(&examplev1.EchoClientStreamRequest{}))
+	// The handler stops reading after an empty message, so sends will eventually return io.EOF.
+	for {
+		if err := stream.Send(&examplev1.EchoClientStreamRequest{Message: "bar"}); err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+	}
+	_, err = stream.CloseAndReceive()
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+}
+
+func TestPersistentProcess(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runner := newCountingRunner(pluginrpc.NewServerRunner(server))
+	client := pluginrpc.NewClient(runner, pluginrpc.ClientWithPersistentProcess())
+	t.Cleanup(func() { require.NoError(t, client.Close()) })
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	for _, message := range []string{"foo", "bar", "baz"} {
+		response, err := echoServiceClient.EchoRequest(
+			context.Background(),
+			&examplev1.EchoRequestRequest{
+				Message: message,
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, message, response.GetMessage())
+	}
+	_, err = echoServiceClient.EchoError(
+		context.Background(),
+		&examplev1.EchoErrorRequest{
+			Code:    pluginrpcv1beta1.Code_CODE_NOT_FOUND,
+			Message: "hello",
+		},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeNotFound, pluginrpcError.Code())
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "bar"},
+		},
+	)
+	require.NoError(t, err)
+	response, err := serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	response, err = serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "bar", response.GetMessage())
+	_, err = serverStream.Receive()
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, serverStream.Close())
+	clientStream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, clientStream.Send(&examplev1.EchoClientStreamRequest{Message: "foo"}))
+	require.NoError(t, clientStream.Send(&examplev1.EchoClientStreamRequest{Message: "bar"}))
+	clientStreamResponse, err := clientStream.CloseAndReceive()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo", "bar"}, clientStreamResponse.GetMessages())
+	// One invocation for --plugin-protocol, and one for --plugin-serve.
+	require.Equal(t, 2, runner.Count())
+}
+
+func TestPersistentProcessInvalidSessionResponse(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := pluginrpc.NewClient(
+		newInvalidSessionRunner(pluginrpc.NewServerRunner(server)),
+		pluginrpc.ClientWithPersistentProcess(),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{Message: "foo"})
+	require.Error(t, err)
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- client.Close() }()
+	select {
+	case err := <-closeErr:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "Close did not return")
+	}
+}
+
+func TestPersistentProcessUndrainedServerStream(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := newClient(server, pluginrpc.ClientWithPersistentProcess())
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	messages := make([]string, 2000)
+	for i := range messages {
+		messages[i] = strings.Repeat("a", 100)
+	}
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: messages,
+		},
+	)
+	require.NoError(t, err)
+	response, err := serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, messages[0], response.GetMessage())
+	// The rest of the server stream is never read, which must not block other calls.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	echoResponse, err := echoServiceClient.EchoRequest(ctx, &examplev1.EchoRequestRequest{Message: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "foo", echoResponse.GetMessage())
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- client.Close() }()
+	select {
+	case err := <-closeErr:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "Close did not return")
+	}
+}
+
+func TestPersistentProcessConcurrent(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := newClient(server, pluginrpc.ClientWithPersistentProcess())
+	t.Cleanup(func() { require.NoError(t, client.Close()) })
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		message := strconv.Itoa(i)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			response, err := echoServiceClient.EchoRequest(
+				context.Background(),
+				&examplev1.EchoRequestRequest{
+					Message: message,
+				},
+			)
+			assert.NoError(t, err)
+			assert.Equal(t, message, response.GetMessage())
+		}()
+	}
+	waitGroup.Wait()
+}
+
+func TestPersistentProcessFallback(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runner := newCountingRunner(newBaseProtocolRunner(pluginrpc.NewServerRunner(server)))
+	client := pluginrpc.NewClient(runner, pluginrpc.ClientWithPersistentProcess())
+	t.Cleanup(func() { require.NoError(t, client.Close()) })
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	for _, message := range []string{"foo", "bar", "baz"} {
+		response, err := echoServiceClient.EchoRequest(
+			context.Background(),
+			&examplev1.EchoRequestRequest{
+				Message: message,
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, message, response.GetMessage())
+	}
+	// One invocation for --plugin-protocol, one for --plugin-spec, and one per call.
+	require.Equal(t, 5, runner.Count())
+}
+
+func TestJSONFormat(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := pluginrpc.NewClient(newBaseProtocolRunner(pluginrpc.NewServerRunner(server)))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", response.GetMessage())
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "bar"},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, serverStream.Close()) })
+	serverStreamResponse, err := serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", serverStreamResponse.GetMessage())
+	clientStream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, clientStream.Send(&examplev1.EchoClientStreamRequest{Message: "foo"}))
+	clientStreamResponse, err := clientStream.CloseAndReceive()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, clientStreamResponse.GetMessages())
+}
+
+func TestJSONFormatCLI(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	stdout := bytes.NewBuffer(nil)
+	// Plugins invoked as a CLI accept and produce JSON, including multi-line JSON on stdin.
+	require.NoError(
+		t,
+		server.Serve(
+			context.Background(),
+			pluginrpc.Env{
+				Args: []string{"echo", "request"},
+				Stdin: strings.NewReader(`{
+  "body": {
+    "@type": "type.googleapis.com/buf.pluginrpc.example.v1.EchoRequestRequest",
+    "message": "hello"
+  }
+}`),
+				Stdout: stdout,
+				Stderr: io.Discard,
+			},
+		),
+	)
+	require.True(t, strings.HasSuffix(stdout.String(), "\n"))
+	protoResponse := &pluginrpcv1beta1.Response{}
+	require.NoError(t, protojson.Unmarshal(stdout.Bytes(), protoResponse))
+	response := &examplev1.EchoRequestResponse{}
+	require.NoError(t, protoResponse.GetBody().UnmarshalTo(response))
+	require.Equal(t, "hello", response.GetMessage())
+}
+
+func TestCallWithStderr(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	clientStderr := bytes.NewBuffer(nil)
+	client := pluginrpc.NewClient(
+		newStderrRunner(pluginrpc.NewServerRunner(server), "hello"),
+		pluginrpc.ClientWithStderr(clientStderr),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	callStderr := bytes.NewBuffer(nil)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithStderr(callStderr),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", callStderr.String())
+	// The --plugin-protocol and --plugin-spec invocations are not attributed to the call.
+	require.Equal(t, "hellohello", clientStderr.String())
+}
+
+func TestCallWithTimeout(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := pluginrpc.NewClient(newBlockingRunner(pluginrpc.NewServerRunner(server)))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithTimeout(10*time.Millisecond),
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo"},
+		},
+		pluginrpc.CallWithTimeout(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, serverStream.Close()) })
+	_, err = serverStream.Receive()
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
+}
+
+func TestCallWithCancel(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := pluginrpc.NewClient(newBlockingRunner(pluginrpc.NewServerRunner(server)))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = echoServiceClient.EchoRequest(
+		ctx,
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeCanceled, pluginrpcError.Code())
+}
+
+func TestCallWithDeadlinePropagated(t *testing.T) {
+	t.Parallel()
+	var deadline time.Time
+	var hasDeadline bool
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+					return func(ctx context.Context, procedurePath string, request any) (any, error) {
+						deadline, hasDeadline = ctx.Deadline()
+						return next(ctx, procedurePath, request)
+					}
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	// The plugin only sees the deadline through the request, as if it were a separate process.
+	client := pluginrpc.NewClient(newDetachingRunner(pluginrpc.NewServerRunner(server)))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+	)
+	require.NoError(t, err)
+	require.False(t, hasDeadline)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	expectedDeadline, _ := ctx.Deadline()
+	_, err = echoServiceClient.EchoRequest(
+		ctx,
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+	)
+	require.NoError(t, err)
+	require.True(t, hasDeadline)
+	require.WithinDuration(t, expectedDeadline, deadline, time.Second)
+}
+
+func TestHandlerContextError(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		err  error
+		code pluginrpc.Code
+	}{
+		{err: context.Canceled, code: pluginrpc.CodeCanceled},
+		{err: context.DeadlineExceeded, code: pluginrpc.CodeDeadlineExceeded},
+		{err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), code: pluginrpc.CodeDeadlineExceeded},
+	} {
+		handlerErr := testCase.err
+		server, err := newServerForHandler(
+			pluginrpc.NewHandler(
+				pluginrpc.HandlerWithInterceptors(
+					func(pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+						return func(context.Context, string, any) (any, error) {
+							return nil, handlerErr
+						}
+					},
+				),
+			),
+		)
+		require.NoError(t, err)
+		echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+		require.NoError(t, err)
+		_, err = echoServiceClient.EchoRequest(
+			context.Background(),
+			&examplev1.EchoRequestRequest{
+				Message: "foo",
+			},
+		)
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError)
+		require.Equal(t, testCase.code, pluginrpcError.Code())
+	}
+}
+
+func TestCallWithResponseHeader(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := newClient(server)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	var header pluginrpc.Header
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithResponseHeader(&header),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", header.Get("message"))
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "bar"},
+		},
+		pluginrpc.CallWithResponseHeader(&header),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, serverStream.Close()) })
+	_, err = serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "2", header.Get("message-count"))
+	clientStream, err := echoServiceClient.EchoClientStream(
+		context.Background(),
+		pluginrpc.CallWithResponseHeader(&header),
+	)
+	require.NoError(t, err)
+	require.NoError(t, clientStream.Send(&examplev1.EchoClientStreamRequest{Message: "foo"}))
+	_, err = clientStream.CloseAndReceive()
+	require.NoError(t, err)
+	require.Equal(t, "1", header.Get("message-count"))
+}
+
+func TestCallWithEnv(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+					return func(ctx context.Context, procedurePath string, request any) (any, error) {
+						pluginrpc.ResponseHeaderFromContext(ctx).Set("environ", strings.Join(pluginrpc.EnvironFromContext(ctx), ","))
+						return next(ctx, procedurePath, request)
+					}
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	for _, persistentProcess := range []bool{false, true} {
+		var clientOptions []pluginrpc.ClientOption
+		if persistentProcess {
+			clientOptions = append(clientOptions, pluginrpc.ClientWithPersistentProcess())
+		}
+		client := newClient(server, clientOptions...)
+		t.Cleanup(func() { require.NoError(t, client.Close()) })
+		echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+		require.NoError(t, err)
+		var header pluginrpc.Header
+		_, err = echoServiceClient.EchoRequest(
+			context.Background(),
+			&examplev1.EchoRequestRequest{
+				Message: "foo",
+			},
+			pluginrpc.CallWithEnv("FOO=bar", "BAZ=qux"),
+			pluginrpc.CallWithResponseHeader(&header),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "FOO=bar,BAZ=qux", header.Get("environ"), "persistentProcess=%v", persistentProcess)
+	}
+}
+
+func TestExecRunnerEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("env is not available on Windows")
+	}
+	t.Setenv("PLUGINRPC_TEST_ALLOWED", "allowed")
+	t.Setenv("PLUGINRPC_TEST_NOT_ALLOWED", "not-allowed")
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(t, pluginrpc.NewExecRunner("env").Run(context.Background(), pluginrpc.Env{Stdout: stdout}))
+	// The environment is empty by default.
+	require.NotContains(t, stdout.String(), "PLUGINRPC_TEST_")
+	stdout.Reset()
+	runner := pluginrpc.NewExecRunner(
+		"env",
+		pluginrpc.ExecRunnerWithEnvAllowlist("PLUGINRPC_TEST_ALLOWED", "PLUGINRPC_TEST_UNSET"),
+		pluginrpc.ExecRunnerWithEnv("PLUGINRPC_TEST_INJECTED=injected", "PLUGINRPC_TEST_OVERRIDDEN=runner"),
+	)
+	require.NoError(
+		t,
+		runner.Run(
+			context.Background(),
+			pluginrpc.Env{
+				Environ: []string{"PLUGINRPC_TEST_OVERRIDDEN=call"},
+				Stdout:  stdout,
+			},
+		),
+	)
+	var environ []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if strings.HasPrefix(line, "PLUGINRPC_TEST_") {
+			environ = append(environ, line)
+		}
+	}
+	require.ElementsMatch(
+		t,
+		[]string{
+			"PLUGINRPC_TEST_ALLOWED=allowed",
+			"PLUGINRPC_TEST_INJECTED=injected",
+			"PLUGINRPC_TEST_OVERRIDDEN=call",
+		},
+		environ,
+	)
+}
+
+func TestCallWithArgs(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	envRecordingRunner := newEnvRecordingRunner(pluginrpc.NewServerRunner(server))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(pluginrpc.NewClient(envRecordingRunner))
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithArgs("--message=bar"),
+	)
+	require.NoError(t, err)
+	// The args are flags that set fields of the request.
+	require.Equal(t, "bar", response.GetMessage())
+	args := envRecordingRunner.LastEnv().Args
+	require.Equal(t, []string{"echo", "request", "--message=bar"}, args[len(args)-3:])
+	// Args are not persisted across calls.
+	response, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	require.NotContains(t, envRecordingRunner.LastEnv().Args, "--message=bar")
+}
+
+func TestCallWithArgsInvalid(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runner := newCountingRunner(pluginrpc.NewServerRunner(server))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(pluginrpc.NewClient(runner))
+	require.NoError(t, err)
+	for _, arg := range []string{"bar", "-message=bar", "--", "--=bar", "---message=bar", "--plugin-output=json"} {
+		_, err := echoServiceClient.EchoRequest(
+			context.Background(),
+			&examplev1.EchoRequestRequest{
+				Message: "foo",
+			},
+			pluginrpc.CallWithArgs("--message=bar", arg),
+		)
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError, arg)
+		require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code(), arg)
+		_, err = echoServiceClient.EchoServerStream(
+			context.Background(),
+			&examplev1.EchoServerStreamRequest{
+				Messages: []string{"foo"},
+			},
+			pluginrpc.CallWithArgs(arg),
+		)
+		require.ErrorAs(t, err, &pluginrpcError, arg)
+		require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code(), arg)
+		_, err = echoServiceClient.EchoClientStream(context.Background(), pluginrpc.CallWithArgs(arg))
+		require.ErrorAs(t, err, &pluginrpcError, arg)
+		require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code(), arg)
+	}
+	// The args are rejected before the plugin is invoked.
+	require.Zero(t, runner.Count())
+}
+
+func TestCallWithDirAndExtraFiles(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	envRecordingRunner := newEnvRecordingRunner(pluginrpc.NewServerRunner(server))
+	client := pluginrpc.NewClient(envRecordingRunner, pluginrpc.ClientWithPersistentProcess())
+	t.Cleanup(func() { require.NoError(t, client.Close()) })
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	file, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, file.Close()) })
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithDir("foo"),
+		pluginrpc.CallWithExtraFiles(file),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	env := envRecordingRunner.LastEnv()
+	// The call is not multiplexed over the persistent process.
+	require.NotContains(t, env.Args, "--plugin-serve")
+	require.Equal(t, "foo", env.Dir)
+	require.Equal(t, []*os.File{file}, env.ExtraFiles)
+}
+
+func TestExecRunnerDirAndExtraFiles(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on Windows")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	runner := pluginrpc.NewExecRunner("sh", pluginrpc.ExecRunnerWithArgs("-c"), pluginrpc.ExecRunnerWithDir(dir))
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(t, runner.Run(context.Background(), pluginrpc.Env{Args: []string{"pwd"}, Stdout: stdout}))
+	require.Equal(t, dir, strings.TrimSpace(stdout.String()))
+	stdout.Reset()
+	require.NoError(t, runner.Run(context.Background(), pluginrpc.Env{Args: []string{"pwd"}, Dir: "sub", Stdout: stdout}))
+	require.Equal(t, filepath.Join(dir, "sub"), strings.TrimSpace(stdout.String()))
+	pipeReader, pipeWriter, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, pipeReader.Close()) })
+	require.NoError(
+		t,
+		runner.Run(
+			context.Background(),
+			pluginrpc.Env{
+				Args:       []string{"echo side >&3"},
+				ExtraFiles: []*os.File{pipeWriter},
+			},
+		),
+	)
+	require.NoError(t, pipeWriter.Close())
+	data, err := io.ReadAll(pipeReader)
+	require.NoError(t, err)
+	require.Equal(t, "side\n", string(data))
+}
+
+func TestServerRunnerPanic(t *testing.T) {
+	t.Parallel()
+	procedure, err := pluginrpc.NewProcedure("/test.PanicService/Panic")
+	require.NoError(t, err)
+	spec, err := pluginrpc.NewSpec([]pluginrpc.Procedure{procedure})
+	require.NoError(t, err)
+	serverRegistrar := pluginrpc.NewServerRegistrar()
+	serverRegistrar.Register(
+		procedure.Path(),
+		func(context.Context, pluginrpc.Env) error {
+			panic("oops") //nolint:forbidigo // tests that panics while serving are recovered
+		},
+	)
+	server, err := pluginrpc.NewServer(spec, serverRegistrar)
+	require.NoError(t, err)
+	runner := pluginrpc.NewServerRunner(server)
+	// The panic is contained, so the Server can still be run afterwards.
+	for i := 0; i < 2; i++ {
+		err = runner.Run(context.Background(), pluginrpc.Env{Args: []string{procedure.Path()}})
+		exitError := &pluginrpc.ExitError{}
+		require.ErrorAs(t, err, &exitError)
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError)
+		require.Equal(t, pluginrpc.CodeInternal, pluginrpcError.Code())
+		require.ErrorContains(t, err, "panic: oops")
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	t.Parallel()
+	for _, handlerOptions := range [][]pluginrpc.HandlerOption{
+		nil,
+		{pluginrpc.HandlerWithPanicStack()},
+	} {
+		server, err := newServerForHandler(
+			pluginrpc.NewHandler(
+				append(
+					handlerOptions,
+					pluginrpc.HandlerWithInterceptors(
+						func(pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+							return func(context.Context, string, any) (any, error) {
+								panic("oops") //nolint:forbidigo // tests that panics in interceptors are recovered
+							}
+						},
+					),
+				)...,
+			),
+		)
+		require.NoError(t, err)
+		for _, clientOptions := range [][]pluginrpc.ClientOption{
+			nil,
+			{pluginrpc.ClientWithPersistentProcess()},
+		} {
+			client := newClient(server, clientOptions...)
+			t.Cleanup(func() { require.NoError(t, client.Close()) })
+			echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+			require.NoError(t, err)
+			_, err = echoServiceClient.EchoRequest(
+				context.Background(),
+				&examplev1.EchoRequestRequest{
+					Message: "hello",
+				},
+			)
+			pluginrpcError := &pluginrpc.Error{}
+			require.ErrorAs(t, err, &pluginrpcError)
+			require.Equal(t, pluginrpc.CodeInternal, pluginrpcError.Code())
+			require.ErrorContains(t, err, "panic: oops")
+			if len(handlerOptions) > 0 {
+				require.ErrorContains(t, err, "goroutine")
+			} else {
+				require.NotContains(t, err.Error(), "goroutine")
+			}
+		}
+		// The plugin writes the error to stdout, and exits with a non-zero exit code.
+		stdout := bytes.NewBuffer(nil)
+		err = server.Serve(
+			context.Background(),
+			pluginrpc.Env{
+				Args:   []string{"echo", "request"},
+				Stdin:  strings.NewReader("{}"),
+				Stdout: stdout,
+			},
+		)
+		exitError := &pluginrpc.ExitError{}
+		require.ErrorAs(t, err, &exitError)
+		require.NotEqual(t, 0, exitError.ExitCode())
+		require.Contains(t, stdout.String(), `"code":"CODE_INTERNAL"`)
+	}
+}
+
+func TestServerRunnerIsolation(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+					return func(ctx context.Context, procedurePath string, request any) (any, error) {
+						// Ignore the context, as a misbehaving plugin might.
+						<-release
+						return next(ctx, procedurePath, request)
+					}
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	runner := pluginrpc.NewServerRunner(server)
+	client := pluginrpc.NewClient(runner)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+		pluginrpc.CallWithTimeout(10*time.Millisecond),
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
+	// Once Run has returned, the Server can no longer write to stdout.
+	stdout := bytes.NewBuffer(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err = runner.Run(
+		ctx,
+		pluginrpc.Env{
+			Args:   []string{"echo", "request"},
+			Stdin:  strings.NewReader(`{"body":{"@type":"type.googleapis.com/buf.pluginrpc.example.v1.EchoRequestRequest","message":"hello"}}`),
+			Stdout: stdout,
+		},
+	)
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeCanceled, pluginrpcError.Code())
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	require.Empty(t, stdout.String())
+}
+
+func TestRunnerRegistry(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runnerRegistry := pluginrpc.NewRunnerRegistry()
+	require.NoError(t, runnerRegistry.RegisterServer("echo", server))
+	require.NoError(t, runnerRegistry.RegisterProgram("external", "pluginrpc-example-server"))
+	require.Error(t, runnerRegistry.RegisterProgram("echo", "pluginrpc-example-server"))
+	require.Error(t, runnerRegistry.RegisterServer("", server))
+	require.Equal(t, []string{"echo", "external"}, runnerRegistry.Names())
+	require.Nil(t, runnerRegistry.Runner("unknown"))
+	runner := runnerRegistry.Runner("echo")
+	require.NotNil(t, runner)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(pluginrpc.NewClient(runner))
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", response.GetMessage())
+}
+
+func TestCallWithResponseHeaderUnsupported(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := pluginrpc.NewClient(newBaseProtocolRunner(pluginrpc.NewServerRunner(server)))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	var header pluginrpc.Header
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithResponseHeader(&header),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+	require.Nil(t, header)
+}
+
+func TestClientWithLogHandler(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+					return func(ctx context.Context, procedurePath string, request any) (any, error) {
+						pluginrpc.LoggerFromContext(ctx).Info("handling", "count", 2, slog.Group("group", "key", "value"))
+						pluginrpc.LoggerFromContext(ctx).Debug("debug")
+						return next(ctx, procedurePath, request)
+					}
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	logs := bytes.NewBuffer(nil)
+	client := pluginrpc.NewClient(
+		newStderrRunner(pluginrpc.NewServerRunner(server), "unstructured\n"),
+		pluginrpc.ClientWithLogHandler(
+			slog.NewJSONHandler(
+				logs,
+				&slog.HandlerOptions{
+					ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+						if len(groups) == 0 && attr.Key == slog.TimeKey {
+							return slog.Attr{}
+						}
+						return attr
+					},
+				},
+			),
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	stderr := bytes.NewBuffer(nil)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithStderr(stderr),
+	)
+	require.NoError(t, err)
+	// Unstructured stderr is still propagated, and debug records are not enabled on the client.
+	require.Equal(t, "unstructured\n", stderr.String())
+	require.JSONEq(
+		t,
+		`{
+			"level": "INFO",
+			"msg": "handling",
+			"procedure": "/buf.pluginrpc.example.v1.EchoService/EchoRequest",
+			"count": 2,
+			"group": {"key": "value"}
+		}`,
+		logs.String(),
+	)
+
+	// Without a slog.Handler, records are written to stderr as text.
+	client = pluginrpc.NewClient(pluginrpc.NewServerRunner(server))
+	echoServiceClient, err = examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	stderr.Reset()
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+		pluginrpc.CallWithStderr(stderr),
+	)
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "level=INFO msg=handling count=2 group.key=value\n")
+}
+
+func TestClientWithRetryPolicy(t *testing.T) {
+	t.Parallel()
+	calls := newCallCounter()
+	server, err := newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithIdempotent()},
+		},
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				newUnavailableInterceptor(calls, 2),
+			),
+		),
+	)
+	require.NoError(t, err)
+	client := newClient(
+		server,
+		pluginrpc.ClientWithRetryPolicy(
+			pluginrpc.RetryPolicy{
+				InitialBackoff: time.Millisecond,
+			},
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", response.GetMessage())
+	require.Equal(t, 3, calls.Get(examplev1pluginrpc.EchoServiceEchoRequestPath))
+	// EchoError is not idempotent, so it is not retried.
+	_, err = echoServiceClient.EchoError(
+		context.Background(),
+		&examplev1.EchoErrorRequest{},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeUnavailable, pluginrpcError.Code())
+	require.Equal(t, 1, calls.Get(examplev1pluginrpc.EchoServiceEchoErrorPath))
+	// Procedures are idempotent if the idempotency_level of the method is NO_SIDE_EFFECTS
+	// or IDEMPOTENT.
+	spec, err := client.Spec(context.Background())
+	require.NoError(t, err)
+	require.True(t, spec.ProcedureForPath(examplev1pluginrpc.EchoServiceEchoListPath).Idempotent())
+	require.False(t, spec.ProcedureForPath(examplev1pluginrpc.EchoServiceEchoErrorPath).Idempotent())
+}
+
+func TestClientWithRetryPolicyMaxAttempts(t *testing.T) {
+	t.Parallel()
+	calls := newCallCounter()
+	server, err := newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithIdempotent()},
+		},
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				newUnavailableInterceptor(calls, 10),
+			),
+		),
+	)
+	require.NoError(t, err)
+	client := newClient(
+		server,
+		pluginrpc.ClientWithRetryPolicy(
+			pluginrpc.RetryPolicy{
+				MaxAttempts:    4,
+				InitialBackoff: time.Millisecond,
+			},
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeUnavailable, pluginrpcError.Code())
+	require.Equal(t, 4, calls.Get(examplev1pluginrpc.EchoServiceEchoRequestPath))
+}
+
+func TestClientWithRetryPolicyBaseProtocol(t *testing.T) {
+	t.Parallel()
+	calls := newCallCounter()
+	server, err := newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithIdempotent()},
+		},
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				newUnavailableInterceptor(calls, 2),
+			),
+		),
+	)
+	require.NoError(t, err)
+	client := pluginrpc.NewClient(
+		newBaseProtocolRunner(pluginrpc.NewServerRunner(server)),
+		pluginrpc.ClientWithRetryPolicy(
+			pluginrpc.RetryPolicy{
+				InitialBackoff: time.Millisecond,
+			},
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	// The plugin cannot report that EchoRequest is idempotent, so the call is not retried,
+	// and the error says so.
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeUnavailable, pluginrpcError.Code())
+	require.ErrorContains(t, err, "not retried")
+	require.Equal(t, 1, calls.Get(examplev1pluginrpc.EchoServiceEchoRequestPath))
+}
+
+func TestClientWithRetryPolicyExitError(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithIdempotent()},
+		},
+		pluginrpc.NewHandler(),
+	)
+	require.NoError(t, err)
+	for _, shouldRetryExitErrors := range []bool{false, true} {
+		runner := newCountingRunner(newExitErrorRunner(pluginrpc.NewServerRunner(server)))
+		retryPolicy := pluginrpc.RetryPolicy{
+			InitialBackoff: time.Millisecond,
+		}
+		if shouldRetryExitErrors {
+			retryPolicy.ShouldRetry = func(err error) bool {
+				exitError := &pluginrpc.ExitError{}
+				return errors.As(err, &exitError)
+			}
+		}
+		echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(
+			pluginrpc.NewClient(runner, pluginrpc.ClientWithRetryPolicy(retryPolicy)),
+		)
+		require.NoError(t, err)
+		_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+		exitError := &pluginrpc.ExitError{}
+		require.ErrorAs(t, err, &exitError)
+		// One invocation for --plugin-protocol, one for --plugin-spec, and one per attempt.
+		if shouldRetryExitErrors {
+			require.Equal(t, 5, runner.Count())
+		} else {
+			require.Equal(t, 3, runner.Count())
+		}
+	}
+}
+
+func TestClientWithRetryPolicyDeadline(t *testing.T) {
+	t.Parallel()
+	calls := newCallCounter()
+	server, err := newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithIdempotent()},
+		},
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				newUnavailableInterceptor(calls, 10),
+			),
+		),
+	)
+	require.NoError(t, err)
+	client := newClient(
+		server,
+		pluginrpc.ClientWithRetryPolicy(
+			pluginrpc.RetryPolicy{
+				InitialBackoff: time.Hour,
+				MaxBackoff:     time.Hour,
+			},
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+		pluginrpc.CallWithTimeout(time.Minute),
+	)
+	// The backoff would exceed the deadline, so the error of the first attempt is returned
+	// without waiting.
+	require.Less(t, time.Since(start), time.Minute)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeUnavailable, pluginrpcError.Code())
+	require.Equal(t, 1, calls.Get(examplev1pluginrpc.EchoServiceEchoRequestPath))
+}
+
+func TestHandlerWithValidator(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForHandler(pluginrpc.NewHandler(pluginrpc.HandlerWithValidator(testValidator{})))
+	require.NoError(t, err)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(newClient(server))
+	require.NoError(t, err)
+	testValidation(t, echoServiceClient)
+}
+
+func TestClientWithValidator(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runner := newCountingRunner(pluginrpc.NewServerRunner(server))
+	client := pluginrpc.NewClient(runner, pluginrpc.ClientWithValidator(testValidator{}))
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	testValidation(t, echoServiceClient)
+	// Invalid requests are never sent to the plugin. There are two calls to retrieve the
+	// protocol and spec, followed by the two unary calls with valid requests, and the two
+	// streaming calls.
+	require.Equal(t, 6, runner.Count())
+}
+
+func TestProtovalidateValidator(t *testing.T) {
+	t.Parallel()
+	validator, err := protovalidate.NewValidator()
+	require.NoError(t, err)
+	require.NoError(
+		t,
+		validator.Validate(
+			&pluginrpcv1beta1.Error{
+				Code:    pluginrpcv1beta1.Code_CODE_INTERNAL,
+				Message: "foo",
+			},
+		),
+	)
+	err = validator.Validate(&pluginrpcv1beta1.Error{Code: pluginrpcv1beta1.Code_CODE_INTERNAL})
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+	require.ErrorContains(t, err, "message")
+	details := pluginrpcError.Details()
+	require.Len(t, details, 1)
+	require.Equal(t, "buf.validate.Violations", details[0].Type())
+	value, err := details[0].Value()
+	require.NoError(t, err)
+	require.Contains(t, protojson.Format(value), `"message"`)
+}
+
+func TestClientInterceptors(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	var calls []string
+	client := newClient(
+		server,
+		pluginrpc.ClientWithInterceptors(
+			newRecordingInterceptor(&calls, "first"),
+			newRecordingInterceptor(&calls, "second"),
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", response.GetMessage())
 	_, err = echoServiceClient.EchoError(
 		context.Background(),
-		&examplev1.EchoErrorRequest{
-			Code:    pluginrpcv1beta1.Code_CODE_DEADLINE_EXCEEDED,
-			Message: "hello",
-		},
+		&examplev1.EchoErrorRequest{
+			Code:    pluginrpcv1beta1.Code_CODE_NOT_FOUND,
+			Message: "foo",
+		},
+	)
+	require.Error(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"first before /buf.pluginrpc.example.v1.EchoService/EchoRequest hello",
+			"second before /buf.pluginrpc.example.v1.EchoService/EchoRequest hello",
+			"second after hello <nil>",
+			"first after hello <nil>",
+			"first before /buf.pluginrpc.example.v1.EchoService/EchoError foo",
+			"second before /buf.pluginrpc.example.v1.EchoService/EchoError foo",
+			"second after  Failed with code not_found: foo",
+			"first after  Failed with code not_found: foo",
+		},
+		calls,
+	)
+}
+
+func TestClientInterceptorsShortCircuit(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := newClient(
+		server,
+		pluginrpc.ClientWithInterceptors(
+			func(pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+				return func(context.Context, string, any) (any, error) {
+					return &examplev1.EchoRequestResponse{Message: "intercepted"}, nil
+				}
+			},
+		),
+	)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "intercepted", response.GetMessage())
+}
+
+func TestHandlerInterceptors(t *testing.T) {
+	t.Parallel()
+	var calls []string
+	server, err := newServerForHandler(
+		pluginrpc.NewHandler(
+			pluginrpc.HandlerWithInterceptors(
+				newRecordingInterceptor(&calls, "first"),
+				newRecordingInterceptor(&calls, "second"),
+			),
+		),
+	)
+	require.NoError(t, err)
+	client := newClient(server)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", response.GetMessage())
+	require.Equal(
+		t,
+		[]string{
+			"first before /buf.pluginrpc.example.v1.EchoService/EchoRequest hello",
+			"second before /buf.pluginrpc.example.v1.EchoService/EchoRequest hello",
+			"second after hello <nil>",
+			"first after hello <nil>",
+		},
+		calls,
+	)
+}
+
+func TestSpecIDMismatch(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	// A new version of the plugin, with different args for EchoRequest.
+	newVersionServer, err := newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithArgs("echo", "request", "v2")},
+		},
+		pluginrpc.NewHandler(),
+	)
+	require.NoError(t, err)
+	runner := newSwappableRunner(pluginrpc.NewServerRunner(server))
+	client := pluginrpc.NewClient(runner)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "foo",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", response.GetMessage())
+
+	runner.Swap(pluginrpc.NewServerRunner(newVersionServer))
+	// Unary calls are retried with the new Spec.
+	response, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "bar",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "bar", response.GetMessage())
+
+	runner.Swap(pluginrpc.NewServerRunner(server))
+	// Streaming calls return an error, and the next call uses the new Spec.
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo"},
+		},
+	)
+	require.NoError(t, err)
+	_, err = serverStream.Receive()
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeFailedPrecondition, pluginrpcError.Code())
+	require.NoError(t, serverStream.Close())
+	serverStream, err = echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo"},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, serverStream.Close()) })
+	serverStreamResponse, err := serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", serverStreamResponse.GetMessage())
+}
+
+func TestInvalidateSpec(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runner := newCountingRunner(pluginrpc.NewServerRunner(server))
+	client := pluginrpc.NewClient(runner)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	require.NoError(t, err)
+	// One invocation for --plugin-protocol, one for --plugin-spec, and one per call.
+	require.Equal(t, 4, runner.Count())
+	client.InvalidateSpec()
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 7, runner.Count())
+}
+
+func TestSpecErrorNotCached(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	runner := newSwappableRunner(errorRunner{})
+	client := pluginrpc.NewClient(runner)
+	echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(client)
+	require.NoError(t, err)
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	require.Error(t, err)
+	runner.Swap(pluginrpc.NewServerRunner(server))
+	_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+	require.NoError(t, err)
+}
+
+func newClient(server pluginrpc.Server, clientOptions ...pluginrpc.ClientOption) pluginrpc.Client {
+	return pluginrpc.NewClient(pluginrpc.NewServerRunner(server), clientOptions...)
+}
+
+func TestServerHelp(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	// Invoking the server without args also prints the help.
+	for _, args := range [][]string{{"--help"}, {"-h"}, nil} {
+		stdout := bytes.NewBuffer(nil)
+		require.NoError(t, server.Serve(context.Background(), pluginrpc.Env{Args: args, Stdout: stdout}))
+		help := stdout.String()
+		require.Contains(t, help, "  echo request\n    Echo the request back.\n")
+		require.Contains(t, help, "    Path:     "+examplev1pluginrpc.EchoServiceEchoRequestPath+"\n")
+		require.Contains(t, help, "    Request:  buf.pluginrpc.example.v1.EchoRequestRequest\n")
+		require.Contains(t, help, "    Response: buf.pluginrpc.example.v1.EchoRequestResponse\n")
+		// EchoList has no args, so it is invoked with its path.
+		require.Contains(t, help, "  "+examplev1pluginrpc.EchoServiceEchoListPath+"\n")
+		require.Contains(t, help, "    Request:  stream buf.pluginrpc.example.v1.EchoClientStreamRequest\n")
+		require.Contains(t, help, "    Response: stream buf.pluginrpc.example.v1.EchoServerStreamResponse\n")
+	}
+	for _, args := range [][]string{
+		{"echo", "request", "--help"},
+		{examplev1pluginrpc.EchoServiceEchoRequestPath, "-h"},
+	} {
+		stdout := bytes.NewBuffer(nil)
+		require.NoError(t, server.Serve(context.Background(), pluginrpc.Env{Args: args, Stdout: stdout}))
+		help := stdout.String()
+		require.True(t, strings.HasPrefix(help, "Usage:\n  echo request [flags]\n\nEcho the request back.\n\n"), help)
+		require.Contains(t, help, "Path:     "+examplev1pluginrpc.EchoServiceEchoRequestPath+"\n")
+		require.NotContains(t, help, "echo error")
+	}
+	// Unrecognized args print the help to stderr, and still fail.
+	for _, args := range [][]string{{"foo"}, {"foo", "--help"}} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		err := server.Serve(context.Background(), pluginrpc.Env{Args: args, Stdout: stdout, Stderr: stderr})
+		require.ErrorContains(t, err, "args not recognized")
+		require.Empty(t, stdout.String())
+		require.Contains(t, stderr.String(), "  echo request\n")
+	}
+}
+
+func TestServerRequestFlags(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	serve := func(stdin string, args ...string) (string, error) {
+		stdout := bytes.NewBuffer(nil)
+		err := server.Serve(
+			context.Background(),
+			pluginrpc.Env{
+				Args:   args,
+				Stdin:  strings.NewReader(stdin),
+				Stdout: stdout,
+				Stderr: io.Discard,
+			},
+		)
+		return stdout.String(), err
+	}
+
+	// Without --plugin-output, the response expected by clients is written.
+	stdout, err := serve("", "echo", "request", "--message=hello")
+	require.NoError(t, err)
+	protoResponse := &pluginrpcv1beta1.Response{}
+	require.NoError(t, protojson.Unmarshal([]byte(stdout), protoResponse))
+	echoRequestResponse := &examplev1.EchoRequestResponse{}
+	require.NoError(t, protoResponse.GetBody().UnmarshalTo(echoRequestResponse))
+	require.Equal(t, "hello", echoRequestResponse.GetMessage())
+
+	stdout, err = serve("", "echo", "request", "--message=hello", "--plugin-output=json")
+	require.NoError(t, err)
+	echoRequestResponse = &examplev1.EchoRequestResponse{}
+	require.NoError(t, protojson.Unmarshal([]byte(stdout), echoRequestResponse))
+	require.Equal(t, "hello", echoRequestResponse.GetMessage())
+
+	stdout, err = serve("", examplev1pluginrpc.EchoServiceEchoRequestPath, "--plugin-output=text", "--message=hello")
+	require.NoError(t, err)
+	echoRequestResponse = &examplev1.EchoRequestResponse{}
+	require.NoError(t, prototext.Unmarshal([]byte(stdout), echoRequestResponse))
+	require.Equal(t, "hello", echoRequestResponse.GetMessage())
+
+	// Flags are merged with the request on stdin, and errors are returned when an output is given.
+	_, err = serve(
+		`{"body":{"@type":"type.googleapis.com/buf.pluginrpc.example.v1.EchoErrorRequest","message":"from stdin"}}`,
+		"echo", "error", "--code=CODE_NOT_FOUND", "--plugin-output=json",
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeNotFound, pluginrpcError.Code())
+	require.Equal(t, "from stdin", pluginrpcError.Unwrap().Error())
+
+	// Repeated fields can be given more than once, and each response is written on its own line.
+	stdout, err = serve(
+		"",
+		examplev1pluginrpc.EchoServiceEchoServerStreamPath,
+		"--messages=foo",
+		"--messages=bar",
+		"--plugin-output=json",
+	)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, expected := range []string{"foo", "bar"} {
+		echoServerStreamResponse := &examplev1.EchoServerStreamResponse{}
+		require.NoError(t, protojson.Unmarshal([]byte(lines[i]), echoServerStreamResponse))
+		require.Equal(t, expected, echoServerStreamResponse.GetMessage())
+	}
+
+	for _, args := range [][]string{
+		{"echo", "request", "--foo=bar"},
+		{"echo", "request", "--message"},
+		{"echo", "request", "--message=foo", "--message=bar"},
+		{"echo", "error", "--code=CODE_FOO"},
+		{examplev1pluginrpc.EchoServiceEchoClientStreamPath, "--message=foo"},
+	} {
+		_, err = serve("", append(args, "--plugin-output=json")...)
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError, args)
+		require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code(), args)
+	}
+	_, err = serve("", "echo", "request", "--plugin-output=yaml")
+	require.ErrorContains(t, err, "invalid --plugin-output")
+	_, err = serve("", "echo", "request", "hello")
+	require.ErrorContains(t, err, "args not recognized")
+
+	stdout, err = serve("", "echo", "error", "--help")
+	require.NoError(t, err)
+	require.Regexp(t, `\n  --code=<buf\.pluginrpc\.v1beta1\.Code> +Set the code field of the request\.\n`, stdout)
+	require.Contains(t, stdout, "  --plugin-output=<json|pretty-json|text>")
+	require.Contains(t, stdout, "  --plugin-input=<json|binary>")
+}
+
+func TestServerBareRequest(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	serve := func(stdin []byte, args ...string) (string, error) {
+		stdout := bytes.NewBuffer(nil)
+		err := server.Serve(
+			context.Background(),
+			pluginrpc.Env{
+				Args:   args,
+				Stdin:  bytes.NewReader(stdin),
+				Stdout: stdout,
+				Stderr: io.Discard,
+			},
+		)
+		return stdout.String(), err
+	}
+
+	// Bare requests in JSON are detected, and responses are then written as indented JSON.
+	stdout, err := serve([]byte(`{"message":"hello"}`), "echo", "request")
+	require.NoError(t, err)
+	require.Greater(t, strings.Count(stdout, "\n"), 1, stdout)
+	echoRequestResponse := &examplev1.EchoRequestResponse{}
+	require.NoError(t, protojson.Unmarshal([]byte(stdout), echoRequestResponse))
+	require.Equal(t, "hello", echoRequestResponse.GetMessage())
+
+	stdout, err = serve([]byte(`{"message":"hello"}`), "echo", "request", "--plugin-output=json")
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(stdout, "\n"), stdout)
+
+	data, err := proto.Marshal(&examplev1.EchoRequestRequest{Message: "hello"})
+	require.NoError(t, err)
+	stdout, err = serve(data, "echo", "request", "--plugin-input=binary")
+	require.NoError(t, err)
+	echoRequestResponse = &examplev1.EchoRequestResponse{}
+	require.NoError(t, protojson.Unmarshal([]byte(stdout), echoRequestResponse))
+	require.Equal(t, "hello", echoRequestResponse.GetMessage())
+
+	// Each line is a separate bare request for client-streaming procedures.
+	stdout, err = serve(
+		[]byte("{\"message\":\"foo\"}\n{\"message\":\"bar\"}\n"),
+		examplev1pluginrpc.EchoServiceEchoClientStreamPath,
+	)
+	require.NoError(t, err)
+	echoClientStreamResponse := &examplev1.EchoClientStreamResponse{}
+	require.NoError(t, protojson.Unmarshal([]byte(stdout), echoClientStreamResponse))
+	require.Equal(t, []string{"foo", "bar"}, echoClientStreamResponse.GetMessages())
+
+	for _, args := range [][]string{
+		{"echo", "request"},
+		{"echo", "request", "--plugin-input=json"},
+		{examplev1pluginrpc.EchoServiceEchoClientStreamPath, "--plugin-input=json"},
+	} {
+		_, err = serve([]byte(`{"foo":"bar"}`), args...)
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError, args)
+		require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code(), args)
+	}
+}
+
+func TestSpecMetadata(t *testing.T) {
+	t.Parallel()
+	server, err := newServerForSpecOptions(
+		"v1.2.3",
+		pluginrpc.SpecWithName("echo-plugin"),
+	)
+	require.NoError(t, err)
+	spec, err := newClient(server).Spec(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "echo-plugin", spec.Name())
+	require.Equal(t, "v1.2.3", spec.Version())
+	// The description defaults to the comments of the service.
+	require.Equal(t, "The service that defines echo operations.", spec.Description())
+	echoRequestProcedure := spec.ProcedureForPath(examplev1pluginrpc.EchoServiceEchoRequestPath)
+	require.NotNil(t, echoRequestProcedure)
+	require.Equal(t, "Echo the request back.", echoRequestProcedure.Description())
+	require.False(t, echoRequestProcedure.Deprecated())
+	echoErrorProcedure := spec.ProcedureForPath(examplev1pluginrpc.EchoServiceEchoErrorPath)
+	require.NotNil(t, echoErrorProcedure)
+	require.True(t, echoErrorProcedure.Deprecated())
+	// The indentation within comments is preserved.
+	echoListProcedure := spec.ProcedureForPath(examplev1pluginrpc.EchoServiceEchoListPath)
+	require.NotNil(t, echoListProcedure)
+	require.Equal(
+		t,
+		"Echo a static list back given an empty request.\n\nThe list is always:\n\n  - foo\n  - bar",
+		echoListProcedure.Description(),
+	)
+	// The metadata is not part of pluginrpcv1beta1.Spec, and is only sent in the spec header.
+	protoSpec, err := pluginrpc.NewSpecForProto(pluginrpc.NewProtoSpec(spec))
+	require.NoError(t, err)
+	require.Empty(t, protoSpec.Version())
+	_, err = pluginrpc.NewClient(
+		newBaseProtocolRunner(pluginrpc.NewServerRunner(server)),
+		pluginrpc.ClientWithMinPluginVersion("v1.0.0"),
+	).Spec(context.Background())
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeFailedPrecondition, pluginrpcError.Code())
+
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(t, server.Serve(context.Background(), pluginrpc.Env{Args: []string{"--help"}, Stdout: stdout}))
+	require.True(t, strings.HasPrefix(stdout.String(), "echo-plugin v1.2.3\n\nThe service that defines echo operations.\n\n"))
+	require.Contains(t, stdout.String(), "    Echo the error specified back as an error.\n    Deprecated.\n")
+
+	_, err = pluginrpc.NewSpec(nil, pluginrpc.SpecWithVersion("1.2"))
+	require.Error(t, err)
+}
+
+func TestClientWithMinPluginVersion(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		version    string
+		minVersion string
+		ok         bool
+	}{
+		{version: "v1.2.3", minVersion: "v1.2.3", ok: true},
+		{version: "v1.2.3", minVersion: "1.2.0", ok: true},
+		{version: "1.10.0", minVersion: "v1.9.0", ok: true},
+		{version: "1.0.0", minVersion: "1.0.0-rc.1", ok: true},
+		{version: "1.0.0-beta", minVersion: "1.0.0-alpha.1", ok: true},
+		{version: "1.0.0-alpha.10", minVersion: "1.0.0-alpha.2", ok: true},
+		{version: "1.0.0+build.2", minVersion: "1.0.0+build.3", ok: true},
+		{version: "v1.2.3", minVersion: "v1.3.0", ok: false},
+		{version: "1.0.0-rc.1", minVersion: "1.0.0", ok: false},
+		{version: "1.0.0-alpha", minVersion: "1.0.0-alpha.1", ok: false},
+		{version: "", minVersion: "v0.1.0", ok: false},
+	} {
+		server, err := newServerForSpecOptions(testCase.version)
+		require.NoError(t, err)
+		echoServiceClient, err := examplev1pluginrpc.NewEchoServiceClient(
+			newClient(server, pluginrpc.ClientWithMinPluginVersion(testCase.minVersion)),
+		)
+		require.NoError(t, err)
+		_, err = echoServiceClient.EchoRequest(context.Background(), &examplev1.EchoRequestRequest{})
+		if testCase.ok {
+			require.NoError(t, err, testCase)
+			continue
+		}
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError, testCase)
+		require.Equal(t, pluginrpc.CodeFailedPrecondition, pluginrpcError.Code(), testCase)
+	}
+}
+
+func TestClientFileDescriptorSet(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	for _, persistentProcess := range []bool{false, true} {
+		var clientOptions []pluginrpc.ClientOption
+		if persistentProcess {
+			clientOptions = append(clientOptions, pluginrpc.ClientWithPersistentProcess())
+		}
+		client := newClient(server, clientOptions...)
+		fileDescriptorSet, err := client.FileDescriptorSet(context.Background())
+		require.NoError(t, err, "persistentProcess=%v", persistentProcess)
+		// Every file comes after its dependencies.
+		seenPaths := make(map[string]struct{})
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			for _, dependency := range fileDescriptorProto.GetDependency() {
+				require.Contains(t, seenPaths, dependency, fileDescriptorProto.GetName())
+			}
+			require.NotContains(t, seenPaths, fileDescriptorProto.GetName())
+			seenPaths[fileDescriptorProto.GetName()] = struct{}{}
+		}
+		require.Contains(t, seenPaths, examplev1.File_buf_pluginrpc_example_v1_example_proto.Path())
+		files, err := protodesc.NewFiles(fileDescriptorSet)
+		require.NoError(t, err)
+		spec, err := client.Spec(context.Background())
+		require.NoError(t, err)
+		for _, procedure := range spec.Procedures() {
+			fullName := strings.ReplaceAll(strings.TrimPrefix(procedure.Path(), "/"), "/", ".")
+			descriptor, err := files.FindDescriptorByName(protoreflect.FullName(fullName))
+			require.NoError(t, err, procedure.Path())
+			_, ok := descriptor.(protoreflect.MethodDescriptor)
+			require.True(t, ok, procedure.Path())
+		}
+	}
+
+	client := pluginrpc.NewClient(newBaseProtocolRunner(pluginrpc.NewServerRunner(server)))
+	_, err = client.FileDescriptorSet(context.Background())
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeUnimplemented, pluginrpcError.Code())
+
+	// Errors from running the plugin are wrapped in the same way as for calls.
+	runner := newSwappableRunner(pluginrpc.NewServerRunner(server))
+	client = pluginrpc.NewClient(runner)
+	_, err = client.Spec(context.Background())
+	require.NoError(t, err)
+	runner.Swap(errorRunner{})
+	_, err = client.FileDescriptorSet(context.Background())
+	exitError := &pluginrpc.ExitError{}
+	require.ErrorAs(t, err, &exitError)
+	require.ErrorContains(t, err, "transient")
+}
+
+func TestDynamicClient(t *testing.T) {
+	t.Parallel()
+	server, err := newServer()
+	require.NoError(t, err)
+	client := newClient(server)
+	dynamicClient := pluginrpc.NewDynamicClient(client)
+
+	response, err := dynamicClient.CallJSON(
+		context.Background(),
+		examplev1pluginrpc.EchoServiceEchoRequestPath,
+		[]byte(`{"message":"hello"}`),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "buf.pluginrpc.example.v1.EchoRequestResponse", string(response.Descriptor().FullName()))
+	responseJSON, err := protojson.Marshal(response)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"message":"hello"}`, string(responseJSON))
+
+	response, err = dynamicClient.Call(
+		context.Background(),
+		examplev1pluginrpc.EchoServiceEchoRequestPath,
+		&examplev1.EchoRequestRequest{Message: "world"},
 	)
+	require.NoError(t, err)
+	echoRequestResponse := &examplev1.EchoRequestResponse{}
+	require.NoError(t, copyDynamicMessage(echoRequestResponse, response))
+	require.Equal(t, "world", echoRequestResponse.GetMessage())
+
+	methodDescriptor, err := dynamicClient.MethodDescriptor(context.Background(), examplev1pluginrpc.EchoServiceEchoServerStreamPath)
+	require.NoError(t, err)
+	require.True(t, methodDescriptor.IsStreamingServer())
+
+	for _, testCase := range []struct {
+		procedurePath string
+		request       proto.Message
+		code          pluginrpc.Code
+	}{
+		{
+			procedurePath: examplev1pluginrpc.EchoServiceEchoRequestPath,
+			request:       &examplev1.EchoListRequest{},
+			code:          pluginrpc.CodeInvalidArgument,
+		},
+		{
+			procedurePath: examplev1pluginrpc.EchoServiceEchoServerStreamPath,
+			request:       &examplev1.EchoServerStreamRequest{},
+			code:          pluginrpc.CodeInvalidArgument,
+		},
+		{
+			procedurePath: "/buf.pluginrpc.example.v1.EchoService/Unknown",
+			request:       &examplev1.EchoRequestRequest{},
+			code:          pluginrpc.CodeUnimplemented,
+		},
+	} {
+		_, err := dynamicClient.Call(context.Background(), testCase.procedurePath, testCase.request)
+		pluginrpcError := &pluginrpc.Error{}
+		require.ErrorAs(t, err, &pluginrpcError, testCase.procedurePath)
+		require.Equal(t, testCase.code, pluginrpcError.Code(), testCase.procedurePath)
+	}
+	_, err = dynamicClient.CallJSON(context.Background(), examplev1pluginrpc.EchoServiceEchoRequestPath, []byte(`{"foo":"bar"}`))
 	pluginrpcError := &pluginrpc.Error{}
 	require.ErrorAs(t, err, &pluginrpcError)
-	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
-	unwrappedErr := pluginrpcError.Unwrap()
-	require.Error(t, unwrappedErr)
-	require.Equal(t, "hello", unwrappedErr.Error())
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+
+	// Plugins that do not support reflection can be called with a resolver.
+	baseProtocolClient := pluginrpc.NewClient(newBaseProtocolRunner(pluginrpc.NewServerRunner(server)))
+	_, err = pluginrpc.NewDynamicClient(baseProtocolClient).MethodDescriptor(
+		context.Background(),
+		examplev1pluginrpc.EchoServiceEchoRequestPath,
+	)
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeUnimplemented, pluginrpcError.Code())
+	response, err = pluginrpc.NewDynamicClient(
+		baseProtocolClient,
+		pluginrpc.DynamicClientWithResolver(protoregistry.GlobalFiles),
+	).CallJSON(
+		context.Background(),
+		examplev1pluginrpc.EchoServiceEchoRequestPath,
+		[]byte(`{"message":"hello"}`),
+	)
+	require.NoError(t, err)
+	require.NoError(t, copyDynamicMessage(echoRequestResponse, response))
+	require.Equal(t, "hello", echoRequestResponse.GetMessage())
 }
 
-func newClient(server pluginrpc.Server, clientOptions ...pluginrpc.ClientOption) pluginrpc.Client {
-	return pluginrpc.NewClient(pluginrpc.NewServerRunner(server), clientOptions...)
+func TestDynamicClientJSONFormat(t *testing.T) {
+	t.Parallel()
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(
+		t,
+		prototext.Unmarshal(
+			[]byte(`
+				name: "dynamic/v1/dynamic.proto"
+				package: "dynamic.v1"
+				syntax: "proto3"
+				message_type: {
+					name: "EchoRequest"
+					field: { name: "message" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "message" }
+				}
+				message_type: {
+					name: "EchoResponse"
+					field: { name: "message" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "message" }
+				}
+				service: {
+					name: "EchoService"
+					method: { name: "Echo" input_type: ".dynamic.v1.EchoRequest" output_type: ".dynamic.v1.EchoResponse" }
+				}
+			`),
+			fileDescriptorProto,
+		),
+	)
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fileDescriptorProto}})
+	require.NoError(t, err)
+	// The types are not linked into the binary, so the JSON format cannot resolve them from
+	// protoregistry.GlobalTypes.
+	_, err = protoregistry.GlobalTypes.FindMessageByName("dynamic.v1.EchoRequest")
+	require.ErrorIs(t, err, protoregistry.NotFound)
+
+	dynamicClient := pluginrpc.NewDynamicClient(
+		pluginrpc.NewClient(newJSONEchoRunner(files, "/dynamic.v1.EchoService/Echo")),
+		pluginrpc.DynamicClientWithResolver(files),
+	)
+	response, err := dynamicClient.CallJSON(
+		context.Background(),
+		"/dynamic.v1.EchoService/Echo",
+		[]byte(`{"message":"hello"}`),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "dynamic.v1.EchoResponse", string(response.Descriptor().FullName()))
+	responseJSON, err := protojson.Marshal(response)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"message":"hello"}`, string(responseJSON))
 }
 
 func newServer(serverOptions ...pluginrpc.ServerOption) (pluginrpc.Server, error) {
-	spec, err := examplev1pluginrpc.EchoServiceSpecBuilder{
-		// Note that EchoList does not have a ProcedureBuilder and will default to path being the only arg.
-		EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithArgs("echo", "request")},
-		EchoError:   []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithArgs("echo", "error")},
-	}.Build()
+	return newServerForHandler(pluginrpc.NewHandler(), serverOptions...)
+}
+
+func newServerForHandler(handler pluginrpc.Handler, serverOptions ...pluginrpc.ServerOption) (pluginrpc.Server, error) {
+	return newServerForSpecBuilder(
+		examplev1pluginrpc.EchoServiceSpecBuilder{
+			// Note that EchoList does not have a ProcedureBuilder and will default to path being the only arg.
+			EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithArgs("echo", "request")},
+			EchoError:   []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithArgs("echo", "error")},
+		},
+		handler,
+		serverOptions...,
+	)
+}
+
+func newServerForSpecBuilder(
+	specBuilder examplev1pluginrpc.EchoServiceSpecBuilder,
+	handler pluginrpc.Handler,
+	serverOptions ...pluginrpc.ServerOption,
+) (pluginrpc.Server, error) {
+	spec, err := specBuilder.Build()
 	if err != nil {
 		return nil, err
 	}
 	serverRegistrar := pluginrpc.NewServerRegistrar()
-	handler := pluginrpc.NewHandler()
 	echoServiceHandler := newEchoServiceHandler()
 	echoServiceServer := examplev1pluginrpc.NewEchoServiceServer(handler, echoServiceHandler)
 	examplev1pluginrpc.RegisterEchoServiceServer(serverRegistrar, echoServiceServer)
 	return pluginrpc.NewServer(spec, serverRegistrar, serverOptions...)
 }
 
+func newServerForSpecOptions(version string, specOptions ...pluginrpc.SpecOption) (pluginrpc.Server, error) {
+	if version != "" {
+		specOptions = append(specOptions, pluginrpc.SpecWithVersion(version))
+	}
+	spec, err := examplev1pluginrpc.EchoServiceSpecBuilder{
+		EchoRequest: []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithArgs("echo", "request")},
+		EchoError:   []pluginrpc.ProcedureOption{pluginrpc.ProcedureWithDeprecated()},
+	}.Build(specOptions...)
+	if err != nil {
+		return nil, err
+	}
+	serverRegistrar := pluginrpc.NewServerRegistrar()
+	examplev1pluginrpc.RegisterEchoServiceServer(
+		serverRegistrar,
+		examplev1pluginrpc.NewEchoServiceServer(pluginrpc.NewHandler(), newEchoServiceHandler()),
+	)
+	return pluginrpc.NewServer(spec, serverRegistrar)
+}
+
 type echoServiceHandler struct{}
 
 func newEchoServiceHandler() *echoServiceHandler {
@@ -141,9 +2321,10 @@ func newEchoServiceHandler() *echoServiceHandler {
 }
 
 func (*echoServiceHandler) EchoRequest(
-	_ context.Context,
+	ctx context.Context,
 	request *examplev1.EchoRequestRequest,
 ) (*examplev1.EchoRequestResponse, error) {
+	pluginrpc.ResponseHeaderFromContext(ctx).Set("message", request.GetMessage())
 	return &examplev1.EchoRequestResponse{
 		Message: request.GetMessage(),
 	}, nil
@@ -161,9 +2342,503 @@ func (*echoServiceHandler) EchoList(
 	}, nil
 }
 
+func (*echoServiceHandler) EchoServerStream(
+	ctx context.Context,
+	request *examplev1.EchoServerStreamRequest,
+	stream *pluginrpc.ServerStream[examplev1.EchoServerStreamResponse],
+) error {
+	pluginrpc.ResponseHeaderFromContext(ctx).Set("message-count", strconv.Itoa(len(request.GetMessages())))
+	for _, message := range request.GetMessages() {
+		if message == "" {
+			return pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "empty message")
+		}
+		if err := stream.Send(&examplev1.EchoServerStreamResponse{Message: message}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*echoServiceHandler) EchoClientStream(
+	ctx context.Context,
+	stream *pluginrpc.ClientStream[examplev1.EchoClientStreamRequest],
+) (*examplev1.EchoClientStreamResponse, error) {
+	var messages []string
+	for {
+		request, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				pluginrpc.ResponseHeaderFromContext(ctx).Set("message-count", strconv.Itoa(len(messages)))
+				return &examplev1.EchoClientStreamResponse{Messages: messages}, nil
+			}
+			return nil, err
+		}
+		if request.GetMessage() == "" {
+			return nil, pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "empty message")
+		}
+		messages = append(messages, request.GetMessage())
+	}
+}
+
 func (*echoServiceHandler) EchoError(
 	_ context.Context,
 	request *examplev1.EchoErrorRequest,
 ) (*examplev1.EchoErrorResponse, error) {
 	return nil, pluginrpc.NewError(pluginrpc.Code(request.GetCode()), errors.New(request.GetMessage()))
 }
+
+type envRecordingRunner struct {
+	delegate pluginrpc.Runner
+	lastEnv  pluginrpc.Env
+	lock     sync.Mutex
+}
+
+func newEnvRecordingRunner(delegate pluginrpc.Runner) *envRecordingRunner {
+	return &envRecordingRunner{
+		delegate: delegate,
+	}
+}
+
+func (e *envRecordingRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	e.lock.Lock()
+	e.lastEnv = env
+	e.lock.Unlock()
+	return e.delegate.Run(ctx, env)
+}
+
+func (e *envRecordingRunner) LastEnv() pluginrpc.Env {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.lastEnv
+}
+
+type countingRunner struct {
+	delegate pluginrpc.Runner
+	count    atomic.Int64
+}
+
+func newCountingRunner(delegate pluginrpc.Runner) *countingRunner {
+	return &countingRunner{
+		delegate: delegate,
+	}
+}
+
+func (c *countingRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	c.count.Add(1)
+	return c.delegate.Run(ctx, env)
+}
+
+func (c *countingRunner) Count() int {
+	return int(c.count.Load())
+}
+
+// baseProtocolRunner simulates a plugin that only supports the base protocol
+// version, by never sending stdin to the plugin.
+type baseProtocolRunner struct {
+	delegate pluginrpc.Runner
+}
+
+func newBaseProtocolRunner(delegate pluginrpc.Runner) *baseProtocolRunner {
+	return &baseProtocolRunner{
+		delegate: delegate,
+	}
+}
+
+func (b *baseProtocolRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if len(env.Args) == 1 && env.Args[0] == "--plugin-protocol" {
+		env.Stdin = nil
+	}
+	return b.delegate.Run(ctx, env)
+}
+
+// invalidSessionRunner simulates a plugin that writes invalid SessionResponses to stdout
+// when invoked with --plugin-serve, until stdout is closed.
+type invalidSessionRunner struct {
+	delegate pluginrpc.Runner
+}
+
+func newInvalidSessionRunner(delegate pluginrpc.Runner) *invalidSessionRunner {
+	return &invalidSessionRunner{
+		delegate: delegate,
+	}
+}
+
+func (i *invalidSessionRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if len(env.Args) != 1 || env.Args[0] != "--plugin-serve" {
+		return i.delegate.Run(ctx, env)
+	}
+	for {
+		// A size of 3, followed by an invalid field tag.
+		if _, err := env.Stdout.Write([]byte{0x03, 0xff, 0xff, 0xff}); err != nil {
+			return err
+		}
+	}
+}
+
+// exitErrorRunner simulates a plugin that exits with an error without sending an *Error
+// on every invocation other than --plugin-protocol and --plugin-spec.
+type exitErrorRunner struct {
+	delegate pluginrpc.Runner
+}
+
+func newExitErrorRunner(delegate pluginrpc.Runner) *exitErrorRunner {
+	return &exitErrorRunner{
+		delegate: delegate,
+	}
+}
+
+func (e *exitErrorRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if len(env.Args) == 1 && (env.Args[0] == "--plugin-protocol" || env.Args[0] == "--plugin-spec") {
+		return e.delegate.Run(ctx, env)
+	}
+	return pluginrpc.NewExitError(2, errors.New("usage error"))
+}
+
+// stderrRunner writes the given message to stderr on every invocation.
+type stderrRunner struct {
+	delegate pluginrpc.Runner
+	message  string
+}
+
+func newStderrRunner(delegate pluginrpc.Runner, message string) *stderrRunner {
+	return &stderrRunner{
+		delegate: delegate,
+		message:  message,
+	}
+}
+
+func (s *stderrRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if _, err := env.Stderr.Write([]byte(s.message)); err != nil {
+		return err
+	}
+	return s.delegate.Run(ctx, env)
+}
+
+// blockingRunner blocks on every invocation other than --plugin-protocol and --plugin-spec
+// until the context is done.
+type blockingRunner struct {
+	delegate pluginrpc.Runner
+}
+
+func newBlockingRunner(delegate pluginrpc.Runner) *blockingRunner {
+	return &blockingRunner{
+		delegate: delegate,
+	}
+}
+
+func (b *blockingRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if len(env.Args) == 1 && strings.HasPrefix(env.Args[0], "--plugin-") {
+		return b.delegate.Run(ctx, env)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// detachingRunner runs its delegate with a context that is never canceled and has no
+// deadline, as is the case for a plugin running in a separate process.
+type detachingRunner struct {
+	delegate pluginrpc.Runner
+}
+
+func newDetachingRunner(delegate pluginrpc.Runner) *detachingRunner {
+	return &detachingRunner{
+		delegate: delegate,
+	}
+}
+
+func (d *detachingRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	return d.delegate.Run(context.WithoutCancel(ctx), env)
+}
+
+// newRecordingInterceptor returns a UnaryInterceptor that records each call in the given slice.
+//
+// The message field of requests and responses is recorded, if present.
+func newRecordingInterceptor(calls *[]string, name string) pluginrpc.UnaryInterceptor {
+	type messageGetter interface {
+		GetMessage() string
+	}
+	getMessage := func(value any) string {
+		if messageGetter, ok := value.(messageGetter); ok {
+			return messageGetter.GetMessage()
+		}
+		return ""
+	}
+	return func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+		return func(ctx context.Context, procedurePath string, request any) (any, error) {
+			*calls = append(*calls, name+" before "+procedurePath+" "+getMessage(request))
+			response, err := next(ctx, procedurePath, request)
+			*calls = append(*calls, fmt.Sprintf("%s after %s %v", name, getMessage(response), err))
+			return response, err
+		}
+	}
+}
+
+// testValidation tests validation with testValidator, either on the client or on the handler.
+func testValidation(t *testing.T, echoServiceClient examplev1pluginrpc.EchoServiceClient) {
+	response, err := echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "hello",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "hello", response.GetMessage())
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "invalid request",
+		},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+	require.Len(t, pluginrpcError.Details(), 1)
+	_, err = echoServiceClient.EchoRequest(
+		context.Background(),
+		&examplev1.EchoRequestRequest{
+			Message: "invalid response",
+		},
+	)
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInternal, pluginrpcError.Code())
+	serverStream, err := echoServiceClient.EchoServerStream(
+		context.Background(),
+		&examplev1.EchoServerStreamRequest{
+			Messages: []string{"foo", "invalid response"},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, serverStream.Close()) })
+	serverStreamResponse, err := serverStream.Receive()
+	require.NoError(t, err)
+	require.Equal(t, "foo", serverStreamResponse.GetMessage())
+	_, err = serverStream.Receive()
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInternal, pluginrpcError.Code())
+	clientStream, err := echoServiceClient.EchoClientStream(context.Background())
+	require.NoError(t, err)
+	// The request is either rejected on Send by the client, or the error is returned from
+	// CloseAndReceive by the handler.
+	err = clientStream.Send(&examplev1.EchoClientStreamRequest{Message: "invalid request"})
+	if err == nil {
+		_, err = clientStream.CloseAndReceive()
+	} else {
+		_, closeErr := clientStream.CloseAndReceive()
+		require.NoError(t, closeErr)
+	}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpcError.Code())
+}
+
+// testValidator is a Validator that rejects requests with the message "invalid request",
+// and responses with the message "invalid response".
+type testValidator struct{}
+
+func (testValidator) Validate(message any) error {
+	protoMessage, ok := message.(proto.Message)
+	if !ok {
+		return fmt.Errorf("expected proto.Message, got %T", message)
+	}
+	messageGetter, ok := message.(interface{ GetMessage() string })
+	if !ok {
+		return nil
+	}
+	invalidMessage := "invalid request"
+	if strings.HasSuffix(string(protoMessage.ProtoReflect().Descriptor().Name()), "Response") {
+		invalidMessage = "invalid response"
+	}
+	if messageGetter.GetMessage() != invalidMessage {
+		return nil
+	}
+	errorDetail, err := pluginrpc.NewErrorDetail(wrapperspb.String(invalidMessage))
+	if err != nil {
+		return err
+	}
+	pluginrpcError := pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "%s", invalidMessage)
+	pluginrpcError.AddDetail(errorDetail)
+	return pluginrpcError
+}
+
+// callCounter counts calls per Procedure path.
+type callCounter struct {
+	counts map[string]int
+	lock   sync.Mutex
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{
+		counts: make(map[string]int),
+	}
+}
+
+// Add records a call to the Procedure with the given path, and returns the number of calls
+// to the Procedure so far, including this call.
+func (c *callCounter) Add(procedurePath string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts[procedurePath]++
+	return c.counts[procedurePath]
+}
+
+func (c *callCounter) Get(procedurePath string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts[procedurePath]
+}
+
+// newUnavailableInterceptor returns a UnaryInterceptor that fails the first failures calls
+// to each Procedure with CodeUnavailable.
+func newUnavailableInterceptor(calls *callCounter, failures int) pluginrpc.UnaryInterceptor {
+	return func(next pluginrpc.UnaryFunc) pluginrpc.UnaryFunc {
+		return func(ctx context.Context, procedurePath string, request any) (any, error) {
+			if calls.Add(procedurePath) <= failures {
+				return nil, pluginrpc.NewErrorf(pluginrpc.CodeUnavailable, "call to %s failed", procedurePath)
+			}
+			return next(ctx, procedurePath, request)
+		}
+	}
+}
+
+// swappableRunner delegates to a Runner that can be swapped, simulating a plugin
+// binary being replaced.
+type swappableRunner struct {
+	delegate pluginrpc.Runner
+	lock     sync.RWMutex
+}
+
+func newSwappableRunner(delegate pluginrpc.Runner) *swappableRunner {
+	return &swappableRunner{
+		delegate: delegate,
+	}
+}
+
+func (s *swappableRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	s.lock.RLock()
+	delegate := s.delegate
+	s.lock.RUnlock()
+	return delegate.Run(ctx, env)
+}
+
+func (s *swappableRunner) Swap(delegate pluginrpc.Runner) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.delegate = delegate
+}
+
+// errorRunner fails on every invocation.
+type errorRunner struct{}
+
+func (errorRunner) Run(context.Context, pluginrpc.Env) error {
+	return errors.New("transient")
+}
+
+// partialServerStreamRunner simulates a plugin that responds to EchoServerStream with a
+// response, followed by a response alongside an error.
+type partialServerStreamRunner struct {
+	delegate pluginrpc.Runner
+}
+
+func newPartialServerStreamRunner(delegate pluginrpc.Runner) *partialServerStreamRunner {
+	return &partialServerStreamRunner{
+		delegate: delegate,
+	}
+}
+
+func (p *partialServerStreamRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if len(env.Args) == 0 || env.Args[len(env.Args)-1] != examplev1pluginrpc.EchoServiceEchoServerStreamPath {
+		return p.delegate.Run(ctx, env)
+	}
+	for _, protoResponse := range []*pluginrpcv1beta1.Response{
+		{},
+		{Error: pluginrpc.NewErrorf(pluginrpc.CodeInternal, "failed after bar").ToProto()},
+	} {
+		message := "foo"
+		if protoResponse.GetError() != nil {
+			message = "bar"
+		}
+		body, err := anypb.New(&examplev1.EchoServerStreamResponse{Message: message})
+		if err != nil {
+			return err
+		}
+		protoResponse.Body = body
+		data, err := protojson.Marshal(protoResponse)
+		if err != nil {
+			return err
+		}
+		if _, err := env.Stdout.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonEchoRunner simulates a plugin that only supports the base protocol version, and thus
+// the JSON format, with a single Procedure that echoes the message field of the request.
+//
+// The request and response types are resolved from the given files.
+type jsonEchoRunner struct {
+	types *dynamicpb.Types
+	path  string
+}
+
+func newJSONEchoRunner(files *protoregistry.Files, path string) *jsonEchoRunner {
+	return &jsonEchoRunner{
+		types: dynamicpb.NewTypes(files),
+		path:  path,
+	}
+}
+
+func (j *jsonEchoRunner) Run(_ context.Context, env pluginrpc.Env) error {
+	if len(env.Args) != 1 {
+		return fmt.Errorf("args not recognized: %v", env.Args)
+	}
+	switch env.Args[0] {
+	case "--plugin-protocol":
+		_, err := env.Stdout.Write([]byte("1\n"))
+		return err
+	case "--plugin-spec":
+		_, err := fmt.Fprintf(env.Stdout, `{"procedures":[{"path":%q}]}`+"\n", j.path)
+		return err
+	case j.path:
+		data, err := io.ReadAll(env.Stdin)
+		if err != nil {
+			return err
+		}
+		protoRequest := &pluginrpcv1beta1.Request{}
+		if err := (protojson.UnmarshalOptions{Resolver: j.types}).Unmarshal(data, protoRequest); err != nil {
+			return err
+		}
+		request, err := anypb.UnmarshalNew(protoRequest.GetBody(), proto.UnmarshalOptions{Resolver: j.types})
+		if err != nil {
+			return err
+		}
+		responseType, err := j.types.FindMessageByName("dynamic.v1.EchoResponse")
+		if err != nil {
+			return err
+		}
+		response := responseType.New()
+		messageField := response.Descriptor().Fields().ByName("message")
+		response.Set(messageField, request.ProtoReflect().Get(request.ProtoReflect().Descriptor().Fields().ByName("message")))
+		body, err := anypb.New(response.Interface())
+		if err != nil {
+			return err
+		}
+		data, err = (protojson.MarshalOptions{Resolver: j.types}).Marshal(&pluginrpcv1beta1.Response{Body: body})
+		if err != nil {
+			return err
+		}
+		_, err = env.Stdout.Write(append(data, '\n'))
+		return err
+	default:
+		return fmt.Errorf("args not recognized: %v", env.Args)
+	}
+}
+
+// copyDynamicMessage replaces the contents of dst with the contents of the dynamic message src.
+func copyDynamicMessage(dst proto.Message, src proto.Message) error {
+	data, err := proto.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, dst)
+}
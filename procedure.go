@@ -22,6 +22,7 @@ import (
 	"slices"
 
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 const minProcedureArgLength = 2
@@ -40,16 +41,73 @@ type Procedure interface {
 	// Arg values may only use the characters [a-zA-Z0-9-_], and never start or end with a dash
 	// or underscore.
 	Args() []string
+	// Idempotent returns true if the Procedure is idempotent.
+	//
+	// Calls to idempotent Procedures can safely be made more than once, and are retried by
+	// Clients with a RetryPolicy.
+	Idempotent() bool
+	// Description returns the description of the Procedure, if any.
+	//
+	// Generated code uses the comments of the RPC as the description. The description is
+	// shown in the help output of Servers.
+	Description() string
+	// MethodDescriptor returns the descriptor of the RPC that the Procedure implements,
+	// if known.
+	//
+	// This is set by generated code, and is used to show the request and response types in
+	// the help output of Servers.
+	MethodDescriptor() protoreflect.MethodDescriptor
+	// Deprecated returns true if the Procedure is deprecated.
+	//
+	// Generated code marks Procedures for deprecated RPCs as deprecated.
+	Deprecated() bool
+	// StreamType returns whether the requests or responses of the Procedure are streamed.
+	//
+	// Generated code sets the StreamType of Procedures for streaming RPCs.
+	StreamType() StreamType
 
 	isProcedure()
 }
 
+// StreamType describes whether the requests or responses of a Procedure are streamed.
+type StreamType int
+
+const (
+	// StreamTypeUnary indicates that a Procedure has a single request and a single response.
+	//
+	// This is the default for Procedures.
+	StreamTypeUnary StreamType = 0
+	// StreamTypeClient indicates that a Procedure has a stream of requests and a single
+	// response.
+	StreamTypeClient StreamType = 1
+	// StreamTypeServer indicates that a Procedure has a single request and a stream of
+	// responses.
+	StreamTypeServer StreamType = 2
+)
+
+// String implements fmt.Stringer.
+func (s StreamType) String() string {
+	switch s {
+	case StreamTypeUnary:
+		return "unary"
+	case StreamTypeClient:
+		return "client_stream"
+	case StreamTypeServer:
+		return "server_stream"
+	default:
+		return fmt.Sprintf("stream_type_%d", int(s))
+	}
+}
+
 // NewProcedure returns a new validated Procedure for the given path.
 func NewProcedure(path string, options ...ProcedureOption) (Procedure, error) {
 	return newProcedure(path, options...)
 }
 
 // NewProcedureForProto returns a new validated Procedure for the given pluginrpcv1beta1.Procedure.
+//
+// pluginrpcv1beta1.Procedures only specify the path and args, so the returned Procedure is
+// never idempotent or deprecated, and has no description or MethodDescriptor.
 func NewProcedureForProto(protoProcedure *pluginrpcv1beta1.Procedure) (Procedure, error) {
 	return newProcedure(protoProcedure.GetPath(), ProcedureWithArgs(protoProcedure.GetArgs()...))
 }
@@ -75,11 +133,68 @@ func ProcedureWithArgs(args ...string) ProcedureOption {
 	}
 }
 
+// ProcedureWithIdempotent specifies that the Procedure is idempotent.
+//
+// Calls to idempotent Procedures can safely be made more than once, and are retried by
+// Clients with a RetryPolicy. As pluginrpcv1beta1.Spec cannot represent this, the
+// idempotent Procedures are sent to Clients in a header alongside the Spec, which requires
+// a plugin and Client that both support spec IDs.
+func ProcedureWithIdempotent() ProcedureOption {
+	return func(procedureOptions *procedureOptions) {
+		procedureOptions.idempotent = true
+	}
+}
+
+// ProcedureWithDescription specifies a description of the Procedure.
+//
+// The description is shown in the help output of Servers.
+func ProcedureWithDescription(description string) ProcedureOption {
+	return func(procedureOptions *procedureOptions) {
+		procedureOptions.description = description
+	}
+}
+
+// ProcedureWithMethodDescriptor specifies the descriptor of the RPC that the Procedure
+// implements.
+//
+// This is used within generated code.
+func ProcedureWithMethodDescriptor(methodDescriptor protoreflect.MethodDescriptor) ProcedureOption {
+	return func(procedureOptions *procedureOptions) {
+		procedureOptions.methodDescriptor = methodDescriptor
+	}
+}
+
+// ProcedureWithDeprecated specifies that the Procedure is deprecated.
+//
+// Deprecated Procedures are marked as such in the help output of Servers, and in the Specs
+// that Clients retrieve.
+func ProcedureWithDeprecated() ProcedureOption {
+	return func(procedureOptions *procedureOptions) {
+		procedureOptions.deprecated = true
+	}
+}
+
+// ProcedureWithStreamType specifies whether the requests or responses of the Procedure are
+// streamed.
+//
+// The StreamType is sent to Clients alongside the Spec, so that Clients can discover which
+// Procedures to call with Client.CallClientStream and Client.CallServerStream.
+func ProcedureWithStreamType(streamType StreamType) ProcedureOption {
+	return func(procedureOptions *procedureOptions) {
+		procedureOptions.streamType = streamType
+	}
+}
+
 // *** PRIVATE ***
 
 type procedure struct {
-	path string
-	args []string
+	path             string
+	args             []string
+	idempotent       bool
+	description      string
+	methodDescriptor protoreflect.MethodDescriptor
+	deprecated       bool
+	streamType       StreamType
 }
 
 func newProcedure(path string, options ...ProcedureOption) (*procedure, error) {
@@ -88,8 +203,13 @@ func newProcedure(path string, options ...ProcedureOption) (*procedure, error) {
 		option(procedureOptions)
 	}
 	procedure := &procedure{
-		path: path,
-		args: procedureOptions.args,
+		path:             path,
+		args:             procedureOptions.args,
+		idempotent:       procedureOptions.idempotent,
+		description:      procedureOptions.description,
+		methodDescriptor: procedureOptions.methodDescriptor,
+		deprecated:       procedureOptions.deprecated,
+		streamType:       procedureOptions.streamType,
 	}
 	if err := validateProcedure(procedure); err != nil {
 		return nil, err
@@ -105,10 +225,35 @@ func (p *procedure) Args() []string {
 	return slices.Clone(p.args)
 }
 
+func (p *procedure) Idempotent() bool {
+	return p.idempotent
+}
+
+func (p *procedure) Description() string {
+	return p.description
+}
+
+func (p *procedure) MethodDescriptor() protoreflect.MethodDescriptor {
+	return p.methodDescriptor
+}
+
+func (p *procedure) Deprecated() bool {
+	return p.deprecated
+}
+
+func (p *procedure) StreamType() StreamType {
+	return p.streamType
+}
+
 func (*procedure) isProcedure() {}
 
 type procedureOptions struct {
-	args []string
+	args             []string
+	idempotent       bool
+	description      string
+	methodDescriptor protoreflect.MethodDescriptor
+	deprecated       bool
+	streamType       StreamType
 }
 
 func newProcedureOptions() *procedureOptions {
@@ -122,6 +267,11 @@ func validateProcedure(procedure *procedure) error {
 	if _, err := url.ParseRequestURI(procedure.path); err != nil {
 		return fmt.Errorf("invalid procedure path: %w", err)
 	}
+	switch procedure.streamType {
+	case StreamTypeUnary, StreamTypeClient, StreamTypeServer:
+	default:
+		return fmt.Errorf("invalid stream type for procedure %q: %v", procedure.path, procedure.streamType)
+	}
 	for _, arg := range procedure.args {
 		if len(arg) < minProcedureArgLength {
 			return fmt.Errorf("arg %q for procedure %q must be at least length %d", arg, procedure.path, minProcedureArgLength)
@@ -17,16 +17,28 @@ package pluginrpc
 import (
 	"fmt"
 
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
+// typeResolver resolves the types of google.protobuf.Any messages in the JSON format, such
+// as the bodies of requests and responses.
+//
+// A nil typeResolver results in protoregistry.GlobalTypes being used.
+type typeResolver interface {
+	protoregistry.ExtensionTypeResolver
+	protoregistry.MessageTypeResolver
+}
+
 // toProtoMessage casts the value into a proto.Message, returning an error
 // if value is not a proto.Message.
 //
 // We use anys in our code instead of proto.Message for forwards-compatibility; right
-// now, we expect jsonpb-encoded values over the wire, but we could easily extend pluginrpc
-// to allow for different codecs, and we could add a Codec interface to this library. Since
-// everything needs to be a proto.Message right now, this isn't a problem.
+// now, we expect protobuf-encoded values over the wire, either JSON or binary, but we
+// could easily extend pluginrpc to allow for different codecs, and we could add a Codec
+// interface to this library. Since everything needs to be a proto.Message right now, this
+// isn't a problem.
 func toProtoMessage(value any) (proto.Message, error) {
 	if value == nil {
 		return nil, nil
@@ -37,3 +49,81 @@ func toProtoMessage(value any) (proto.Message, error) {
 	}
 	return message, nil
 }
+
+// marshalProto marshals the message in the given format.
+func marshalProto(format format, message proto.Message) ([]byte, error) {
+	return marshalProtoWithTypeResolver(format, message, nil)
+}
+
+// marshalProtoWithTypeResolver marshals the message in the given format, resolving the
+// types of google.protobuf.Any messages with the typeResolver.
+func marshalProtoWithTypeResolver(format format, message proto.Message, typeResolver typeResolver) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		return protojson.MarshalOptions{Resolver: typeResolver}.Marshal(message)
+	case formatBinary:
+		return proto.Marshal(message)
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+// unmarshalProto unmarshals the data in the given format into the message.
+func unmarshalProto(format format, data []byte, message proto.Message) error {
+	return unmarshalProtoWithTypeResolver(format, data, message, nil)
+}
+
+// unmarshalProtoWithTypeResolver unmarshals the data in the given format into the message,
+// resolving the types of google.protobuf.Any messages with the typeResolver.
+func unmarshalProtoWithTypeResolver(format format, data []byte, message proto.Message, typeResolver typeResolver) error {
+	switch format {
+	case formatJSON:
+		return protojson.UnmarshalOptions{Resolver: typeResolver}.Unmarshal(data, message)
+	case formatBinary:
+		return proto.Unmarshal(data, message)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+// isExtensionOf returns true if none of the field numbers of the extension message are
+// used by the fields of the message.
+//
+// Extension messages hold fields that are encoded as unknown fields of upstream messages
+// that cannot be modified. If the upstream message ever defines a field with the same
+// number, the extension can no longer be used.
+func isExtensionOf(message proto.Message, extension proto.Message) bool {
+	fields := message.ProtoReflect().Descriptor().Fields()
+	extensionFields := extension.ProtoReflect().Descriptor().Fields()
+	for i := 0; i < extensionFields.Len(); i++ {
+		if fields.ByNumber(extensionFields.Get(i).Number()) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// copyProtoMessage replaces the contents of dst with the contents of src.
+//
+// Both dst and src must be proto.Messages of the same type.
+func copyProtoMessage(dst any, src any) error {
+	dstMessage, err := toProtoMessage(dst)
+	if err != nil {
+		return err
+	}
+	srcMessage, err := toProtoMessage(src)
+	if err != nil {
+		return err
+	}
+	if dstMessage == nil || srcMessage == nil {
+		return nil
+	}
+	dstName := dstMessage.ProtoReflect().Descriptor().FullName()
+	srcName := srcMessage.ProtoReflect().Descriptor().FullName()
+	if dstName != srcName {
+		return fmt.Errorf("cannot copy %s into %s", srcName, dstName)
+	}
+	proto.Reset(dstMessage)
+	proto.Merge(dstMessage, srcMessage)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protovalidate provides a pluginrpc.Validator that uses protovalidate.
+//
+// This is a separate package so that programs that use pluginrpc without protovalidate
+// do not depend on protovalidate.
+package protovalidate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/pluginrpc-go"
+	protovalidatego "github.com/bufbuild/protovalidate-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewValidator returns a new pluginrpc.Validator that validates proto.Messages according
+// to their buf.validate annotations, using protovalidate.
+//
+// If a message is invalid, the returned *pluginrpc.Error has a pluginrpc.ErrorDetail
+// containing the buf.validate.Violations.
+func NewValidator() (pluginrpc.Validator, error) {
+	return newValidator()
+}
+
+// *** PRIVATE ***
+
+type validator struct {
+	validator *protovalidatego.Validator
+}
+
+func newValidator() (*validator, error) {
+	protovalidateValidator, err := protovalidatego.New()
+	if err != nil {
+		return nil, err
+	}
+	return &validator{
+		validator: protovalidateValidator,
+	}, nil
+}
+
+func (v *validator) Validate(message any) error {
+	protoMessage, ok := message.(proto.Message)
+	if !ok {
+		return fmt.Errorf("expected proto.Message, got %T", message)
+	}
+	err := v.validator.Validate(protoMessage)
+	validationError := &protovalidatego.ValidationError{}
+	if !errors.As(err, &validationError) {
+		return err
+	}
+	pluginrpcError := pluginrpc.NewError(pluginrpc.CodeInvalidArgument, err)
+	errorDetail, err := pluginrpc.NewErrorDetail(validationError.ToProto())
+	if err != nil {
+		return err
+	}
+	pluginrpcError.AddDetail(errorDetail)
+	return pluginrpcError
+}
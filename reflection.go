@@ -0,0 +1,48 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newFileDescriptorSet returns a FileDescriptorSet containing the files that define the
+// MethodDescriptors of the Procedures of the Spec, along with all of their dependencies.
+//
+// Every file comes after its dependencies. Procedures without a MethodDescriptor are skipped.
+func newFileDescriptorSet(spec Spec) *descriptorpb.FileDescriptorSet {
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]struct{})
+	var addFile func(protoreflect.FileDescriptor)
+	addFile = func(fileDescriptor protoreflect.FileDescriptor) {
+		if _, ok := seen[fileDescriptor.Path()]; ok {
+			return
+		}
+		seen[fileDescriptor.Path()] = struct{}{}
+		imports := fileDescriptor.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+		fileDescriptorSet.File = append(fileDescriptorSet.File, protodesc.ToFileDescriptorProto(fileDescriptor))
+	}
+	for _, procedure := range spec.Procedures() {
+		if methodDescriptor := procedure.MethodDescriptor(); methodDescriptor != nil {
+			addFile(methodDescriptor.ParentFile())
+		}
+	}
+	return fileDescriptorSet
+}
@@ -15,13 +15,18 @@
 package pluginrpc
 
 import (
+	"encoding/json"
+
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
-func marshalRequest(request any) ([]byte, error) {
+// marshalRequest marshals the request as a pluginrpcv1beta1.Request in the given format.
+//
+// The typeResolver may be nil. See typeResolver.
+func marshalRequest(format format, request any, typeResolver typeResolver) ([]byte, error) {
 	requestMessage, err := toProtoMessage(request)
 	if err != nil {
 		return nil, err
@@ -33,15 +38,15 @@ func marshalRequest(request any) ([]byte, error) {
 	protoRequest := &pluginrpcv1beta1.Request{
 		Body: body,
 	}
-	return protojson.Marshal(protoRequest)
+	return marshalProtoWithTypeResolver(format, protoRequest, typeResolver)
 }
 
-func unmarshalRequest(data []byte, request any) error {
+func unmarshalRequest(format format, data []byte, request any) error {
 	if len(data) == 0 {
 		return nil
 	}
 	protoRequest := &pluginrpcv1beta1.Request{}
-	if err := protojson.Unmarshal(data, protoRequest); err != nil {
+	if err := unmarshalProto(format, data, protoRequest); err != nil {
 		return err
 	}
 	if body := protoRequest.GetBody(); body != nil {
@@ -53,3 +58,54 @@ func unmarshalRequest(data []byte, request any) error {
 	}
 	return nil
 }
+
+// unmarshalRequestOrBareRequest unmarshals the data as a pluginrpcv1beta1.Request, unless
+// the data is JSON that is not a pluginrpcv1beta1.Request, in which case the data is
+// unmarshalled as a bare request, that is, the request message itself.
+//
+// Returns true if the data was a bare request, even if the bare request was invalid. Clients
+// always send pluginrpcv1beta1.Requests, but bare requests allow plugins to be invoked as a
+// CLI, for example:
+//
+//	echo '{"message":"hello"}' | plugin-server /pkg.Service/Method
+//
+// Invalid bare requests result in an *Error with CodeInvalidArgument.
+func unmarshalRequestOrBareRequest(format format, data []byte, request any) (bool, error) {
+	if format != formatJSON || !isBareJSONRequest(data) {
+		return false, unmarshalRequest(format, data, request)
+	}
+	requestMessage, err := toProtoMessage(request)
+	if err != nil {
+		return false, err
+	}
+	if err := protojson.Unmarshal(data, requestMessage); err != nil {
+		return true, NewErrorf(CodeInvalidArgument, "invalid request: %w", err)
+	}
+	return true, nil
+}
+
+// isBareJSONRequest returns true if the data is a JSON object that is not a
+// pluginrpcv1beta1.Request.
+//
+// A pluginrpcv1beta1.Request only has a body, which is a google.protobuf.Any that
+// always has a type. Data that is not a JSON object is left to unmarshalRequest to
+// report as invalid.
+func isBareJSONRequest(data []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	for key, value := range fields {
+		if key != "body" {
+			return true
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(value, &body); err != nil {
+			return true
+		}
+		if _, ok := body["@type"]; body != nil && !ok {
+			return true
+		}
+	}
+	return false
+}
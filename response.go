@@ -16,14 +16,13 @@ package pluginrpc
 
 import (
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
-func marshalResponse(response any, err error) ([]byte, error) {
+func marshalResponse(format format, response any, err error) ([]byte, error) {
 	var body *anypb.Any
-	if response != nil {
+	if !isNilResponse(response) {
 		responseMessage, err := toProtoMessage(response)
 		if err != nil {
 			return nil, err
@@ -37,15 +36,19 @@ func marshalResponse(response any, err error) ([]byte, error) {
 		Body:  body,
 		Error: WrapError(err).ToProto(),
 	}
-	return protojson.Marshal(protoResponse)
+	return marshalProto(format, protoResponse)
 }
 
-func unmarshalResponse(data []byte, response any) error {
+// unmarshalResponse unmarshals the data as a pluginrpcv1beta1.Response in the given format,
+// and unmarshals the body into the response.
+//
+// The typeResolver may be nil. See typeResolver.
+func unmarshalResponse(format format, data []byte, response any, typeResolver typeResolver) error {
 	if len(data) == 0 {
 		return nil
 	}
 	protoResponse := &pluginrpcv1beta1.Response{}
-	if err := protojson.Unmarshal(data, protoResponse); err != nil {
+	if err := unmarshalProtoWithTypeResolver(format, data, protoResponse, typeResolver); err != nil {
 		return err
 	}
 	if body := protoResponse.GetBody(); body != nil {
@@ -58,7 +61,55 @@ func unmarshalResponse(data []byte, response any) error {
 		}
 	}
 	if protoError := protoResponse.GetError(); protoError != nil {
+		if protoResponse.GetBody() != nil {
+			return newPartialResponseError(NewErrorForProto(protoError))
+		}
+		return NewErrorForProto(protoError)
+	}
+	return nil
+}
+
+// isNilResponse returns true if the response is nil, including if the response is a
+// typed nil proto.Message, as is returned by generated code.
+func isNilResponse(response any) bool {
+	if response == nil {
+		return true
+	}
+	message, ok := response.(proto.Message)
+	return ok && !message.ProtoReflect().IsValid()
+}
+
+// unmarshalExitError returns the *Error written by a plugin that exited with a non-zero exit
+// code, given the data written to stdout, or nil if the plugin did not write a response with
+// only an error.
+//
+// Plugins exit with a non-zero exit code after writing an error if they did not complete
+// normally, for example if a handler panicked.
+func unmarshalExitError(data []byte) *Error {
+	_, data, err := decodeHeaderFrame(data)
+	if err != nil {
+		return nil
+	}
+	data, format, err := decodeFrame(data)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	protoResponse := &pluginrpcv1beta1.Response{}
+	if err := unmarshalProto(format, data, protoResponse); err != nil {
+		return nil
+	}
+	if protoError := protoResponse.GetError(); protoError != nil && protoResponse.GetBody() == nil {
 		return NewErrorForProto(protoError)
 	}
 	return nil
 }
+
+// unmarshalResponseFrame unmarshals data that contains at most a single response frame,
+// in either format.
+func unmarshalResponseFrame(data []byte, response any, typeResolver typeResolver) error {
+	data, format, err := decodeFrame(data)
+	if err != nil {
+		return err
+	}
+	return unmarshalResponse(format, data, response, typeResolver)
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+)
+
+// RetryPolicy specifies how a Client retries failed calls.
+//
+// Only unary calls to idempotent Procedures are retried, as specified with
+// ProcedureWithIdempotent. Calls that returned a partial response are never retried.
+//
+// Between attempts, the Client waits for a backoff that starts at InitialBackoff, and doubles
+// after every attempt up to MaxBackoff. A random jitter of up to half the backoff is
+// subtracted, so that Clients do not retry in lockstep. If the context of the call is done
+// before the next attempt, or its deadline would be exceeded while waiting, the error of the
+// last attempt is returned.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first attempt.
+	//
+	// If MaxAttempts is 0, 3 attempts are made. If MaxAttempts is 1, calls are not retried.
+	MaxAttempts int
+	// InitialBackoff is the backoff before the first retry.
+	//
+	// If InitialBackoff is 0, an initial backoff of 100 milliseconds is used.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum backoff between attempts.
+	//
+	// If MaxBackoff is 0, a maximum backoff of 5 seconds is used.
+	MaxBackoff time.Duration
+	// ShouldRetry returns true if the attempt that failed with the given error should be retried.
+	//
+	// If ShouldRetry is nil, attempts are retried if they failed with an *Error with
+	// CodeUnavailable or CodeResourceExhausted. Attempts are not retried if the plugin exited
+	// with an *ExitError without sending an *Error, as this is typically deterministic, for
+	// example if the plugin did not recognize its args. To retry these, use a ShouldRetry
+	// that returns true for *ExitErrors.
+	ShouldRetry func(err error) bool
+}
+
+// *** PRIVATE ***
+
+// shouldRetry returns true if the attempt with the given number, starting at 1, should be
+// retried after failing with the given error.
+func (r *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if HasPartialResponse(err) {
+		return false
+	}
+	maxAttempts := r.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	if attempt >= maxAttempts {
+		return false
+	}
+	if r.ShouldRetry != nil {
+		return r.ShouldRetry(err)
+	}
+	return isRetryableError(err)
+}
+
+// getBackoff returns the backoff before retrying the attempt with the given number,
+// starting at 1, including jitter.
+func (r *RetryPolicy) getBackoff(attempt int) time.Duration {
+	backoff := r.InitialBackoff
+	if backoff == 0 {
+		backoff = defaultRetryInitialBackoff
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxBackoff)
+	if jitter := int64(backoff / 2); jitter > 0 {
+		backoff -= time.Duration(rand.Int63n(jitter + 1)) //nolint:gosec // jitter does not need a cryptographically secure source
+	}
+	return backoff
+}
+
+// waitForRetry waits for the backoff before retrying the attempt with the given number.
+//
+// Returns false if the context is done, or if its deadline would be exceeded while waiting.
+func (r *RetryPolicy) waitForRetry(ctx context.Context, attempt int) bool {
+	backoff := r.getBackoff(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= backoff {
+		return false
+	}
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// isRetryableError is the default RetryPolicy.ShouldRetry.
+func isRetryableError(err error) bool {
+	pluginrpcError := &Error{}
+	if !errors.As(err, &pluginrpcError) {
+		return false
+	}
+	code := pluginrpcError.Code()
+	return code == CodeUnavailable || code == CodeResourceExhausted
+}
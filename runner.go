@@ -18,21 +18,29 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
+	"time"
 )
 
 var emptyEnv = []string{"__EMPTY_ENV=1"}
 
 // Runner runs external commands.
 //
-// Runners should not proxy any environment variables to the commands they run.
+// Runners should not proxy any environment variables to the commands they run, other
+// than those explicitly configured.
 type Runner interface {
 	// Run runs the external command with the given environment.
 	//
-	// The environment variables are always cleared before running the command.
+	// The environment variables are always cleared before running the command, and then
+	// only the environment variables given by Env.Environ, along with any environment
+	// variables configured on the Runner, are set.
 	// If no stdin, stdout, or stderr are provided, the equivalent of /dev/null are given to the command.
-	// The command is run in the context of the current working directory.
+	// The command is run in Env.Dir if set, and otherwise in the default working directory
+	// of the Runner, which is the current working directory unless configured otherwise.
+	// Env.ExtraFiles are given to the command as additional file descriptors.
 	//
 	// If there is an exit error, it is returned as a *ExitError.
 	Run(ctx context.Context, env Env) error
@@ -40,6 +48,9 @@ type Runner interface {
 
 // NewExecRunner returns a new Runner that uses os/exec to call the given
 // external command given by the program name.
+//
+// If the context is done before the command exits, the command is killed, and Run returns
+// an *ExitError wrapping an *Error with CodeCanceled or CodeDeadlineExceeded.
 func NewExecRunner(programName string, options ...ExecRunnerOption) Runner {
 	return newExecRunner(programName, options...)
 }
@@ -61,9 +72,151 @@ func ExecRunnerWithArgs(args ...string) ExecRunnerOption {
 	}
 }
 
-// NewServerRunner returns a new Runner that directly calls the server.
+// ExecRunnerWithEnv returns a new ExecRunnerOption that sets the given environment variables,
+// in the form "key=value", for every invocation of the program.
+//
+// Environment variables given by Env.Environ take precedence.
+func ExecRunnerWithEnv(env ...string) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.env = append(execRunnerOptions.env, env...)
+	}
+}
+
+// ExecRunnerWithEnvAllowlist returns a new ExecRunnerOption that passes the given environment
+// variables of the current process through to every invocation of the program, if set.
+//
+// For example, ExecRunnerWithEnvAllowlist("HOME", "PATH") will result in the program having
+// access to HOME and PATH. Environment variables given by ExecRunnerWithEnv or Env.Environ
+// take precedence.
+func ExecRunnerWithEnvAllowlist(keys ...string) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.envAllowlist = append(execRunnerOptions.envAllowlist, keys...)
+	}
+}
+
+// ExecRunnerWithDir returns a new ExecRunnerOption that runs the program in the given
+// working directory.
+//
+// If Env.Dir is set, it takes precedence. If Env.Dir is a relative path, it is interpreted
+// relative to the given directory.
+//
+// The default is to use the current working directory.
+func ExecRunnerWithDir(dir string) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.dir = dir
+	}
+}
+
+// ExecRunnerWithMaxOutputSize returns a new ExecRunnerOption that limits the number of bytes
+// the program can write to each of stdout and stderr.
+//
+// If the program exceeds the limit, it is killed, and Run returns an *ExitError wrapping an
+// *Error with CodeResourceExhausted. When used with ClientWithPersistentProcess, the limit
+// applies to the entire lifetime of the plugin process, not to individual calls.
+//
+// The default is no limit.
+func ExecRunnerWithMaxOutputSize(maxOutputSize int64) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.maxOutputSize = maxOutputSize
+	}
+}
+
+// ExecRunnerWithMemoryLimit returns a new ExecRunnerOption that limits the virtual memory
+// of the program to the given number of bytes, via RLIMIT_AS.
+//
+// Allocations beyond the limit fail, which typically results in the program exiting with an
+// error. See ExecRunnerWithCPUTimeLimit for how limits are applied.
+//
+// This is only supported on Linux. On other platforms, Run will return an error.
+//
+// The default is no limit.
+func ExecRunnerWithMemoryLimit(memoryLimit uint64) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.memoryLimit = memoryLimit
+	}
+}
+
+// ExecRunnerWithCPUTimeLimit returns a new ExecRunnerOption that limits the CPU time of the
+// program, via RLIMIT_CPU. The limit is rounded up to the nearest second.
+//
+// If the program exceeds the limit, it is killed, and Run returns an *ExitError wrapping an
+// *Error with CodeResourceExhausted.
 //
-// This is primarily used for testing.
+// The limit is applied via prlimit immediately after the program is started, so the program
+// runs without the limit for a brief window, and any processes it starts within that window
+// are not limited. Processes started after the limit is applied inherit it, each with its own
+// limit. Resource limits are not a sandbox: they do not restrict what the program can access.
+//
+// This is only supported on Linux. On other platforms, Run will return an error.
+//
+// The default is no limit.
+func ExecRunnerWithCPUTimeLimit(cpuTimeLimit time.Duration) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.cpuTimeLimit = cpuTimeLimit
+	}
+}
+
+// ExecRunnerWithKillProcessGroup returns a new ExecRunnerOption that runs the program in its
+// own process group, and kills the entire process group when the context is cancelled.
+//
+// This ensures that any processes started by the program are also killed.
+//
+// This is only supported on Linux. On other platforms, Run will return an error.
+//
+// The default is to only kill the program itself.
+func ExecRunnerWithKillProcessGroup() ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.killProcessGroup = true
+	}
+}
+
+// ExecRunnerWithNoNewPrivileges returns a new ExecRunnerOption that runs the program with
+// the no_new_privs attribute set, so that the program and its children cannot gain
+// privileges, for example via setuid binaries.
+//
+// This is only supported on Linux. On other platforms, Run will return an error.
+//
+// The default is to not set no_new_privs.
+func ExecRunnerWithNoNewPrivileges() ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.noNewPrivileges = true
+	}
+}
+
+// ExecRunnerWithGracePeriod returns a new ExecRunnerOption that gives the program the given
+// grace period to exit when the context is cancelled.
+//
+// When the context is cancelled, the program is sent SIGTERM, which programs using Main handle
+// by cancelling their own context. If the program has not exited once the grace period has
+// elapsed, it is killed. On Windows, where SIGTERM is not supported, the program is killed
+// immediately.
+//
+// With ExecRunnerWithKillProcessGroup, the entire process group is sent SIGTERM, and is killed
+// once the grace period has elapsed if the program has not exited. If the program exits within
+// the grace period, the process group is not killed, as its ID can then be reused.
+//
+// The default is to kill the program immediately when the context is cancelled.
+func ExecRunnerWithGracePeriod(gracePeriod time.Duration) ExecRunnerOption {
+	return func(execRunnerOptions *execRunnerOptions) {
+		execRunnerOptions.gracePeriod = gracePeriod
+	}
+}
+
+// NewServerRunner returns a new Runner that directly calls the server in-process.
+//
+// This allows plugins that are compiled into the host to be run without the cost of
+// launching a process, and is also useful for testing.
+//
+// The Server is called on a separate goroutine, and stdin, stdout, and stderr are piped to
+// and from the Server as data is read and written. Once Run returns, the Server can no
+// longer read from or write to the stdio given in the Env. If the context is done before
+// the Server returns, Run returns immediately with an *ExitError wrapping an *Error with
+// CodeCanceled or CodeDeadlineExceeded, and the Server is left to return on its own once
+// it observes that its context is done. If the Server panics, Run returns an *ExitError
+// wrapping an *Error with CodeInternal.
+//
+// As with a separate process, the Server sees the environment variables given by
+// Env.Environ via EnvironFromContext, and not the environment of the host.
 func NewServerRunner(server Server, _ ...ServerRunnerOption) Runner {
 	return newServerRunner(server)
 }
@@ -74,8 +227,17 @@ type ServerRunnerOption func(*serverRunnerOptions)
 // *** PRIVATE ***
 
 type execRunner struct {
-	programName     string
-	programBaseArgs []string
+	programName      string
+	programBaseArgs  []string
+	env              []string
+	envAllowlist     []string
+	dir              string
+	maxOutputSize    int64
+	memoryLimit      uint64
+	cpuTimeLimit     time.Duration
+	killProcessGroup bool
+	noNewPrivileges  bool
+	gracePeriod      time.Duration
 }
 
 func newExecRunner(programName string, options ...ExecRunnerOption) *execRunner {
@@ -84,15 +246,41 @@ func newExecRunner(programName string, options ...ExecRunnerOption) *execRunner
 		option(execRunnerOptions)
 	}
 	return &execRunner{
-		programName:     programName,
-		programBaseArgs: execRunnerOptions.args,
+		programName:      programName,
+		programBaseArgs:  execRunnerOptions.args,
+		env:              execRunnerOptions.env,
+		envAllowlist:     execRunnerOptions.envAllowlist,
+		dir:              execRunnerOptions.dir,
+		maxOutputSize:    execRunnerOptions.maxOutputSize,
+		memoryLimit:      execRunnerOptions.memoryLimit,
+		cpuTimeLimit:     execRunnerOptions.cpuTimeLimit,
+		killProcessGroup: execRunnerOptions.killProcessGroup,
+		noNewPrivileges:  execRunnerOptions.noNewPrivileges,
+		gracePeriod:      execRunnerOptions.gracePeriod,
 	}
 }
 
 func (e *execRunner) Run(ctx context.Context, env Env) error {
-	cmd := exec.CommandContext(ctx, e.programName, append(slices.Clone(e.programBaseArgs), env.Args...)...)
-	// We want to make sure the command has access to no env vars, as the default is the current env.
-	cmd.Env = emptyEnv
+	// We cancel the command context to kill the command if it exceeds the maximum output size.
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, e.programName, append(slices.Clone(e.programBaseArgs), env.Args...)...)
+	// stopKill stops any kill of the command that cancelCmd scheduled. Wait waits for Cancel
+	// to return, so this is set before Wait returns.
+	var stopKill func()
+	cmd.Cancel = func() error {
+		var err error
+		stopKill, err = e.cancelCmd(cmd)
+		return err
+	}
+	// If the command does not exit within the grace period after being cancelled, os/exec
+	// kills it. This is a no-op if the grace period is 0.
+	cmd.WaitDelay = e.gracePeriod
+	// We want to make sure the command has access to no env vars other than those
+	// explicitly given, as the default is the current env.
+	cmd.Env = e.getCmdEnv(env.Environ)
+	cmd.Dir = e.getCmdDir(env.Dir)
+	cmd.ExtraFiles = env.ExtraFiles
 	// If the user did not specify various stdio, we want to make sure
 	// the command has access to no stdio.
 	if env.Stdin == nil {
@@ -110,22 +298,117 @@ func (e *execRunner) Run(ctx context.Context, env Env) error {
 	} else {
 		cmd.Stderr = env.Stderr
 	}
-	// The default behavior for dir is what we want already, i.e. the current
-	// working directory.
-
-	if err := cmd.Run(); err != nil {
+	var stdoutLimitWriter, stderrLimitWriter *outputLimitWriter
+	if e.maxOutputSize > 0 {
+		stdoutLimitWriter = newOutputLimitWriter(cmd.Stdout, e.maxOutputSize, cancel)
+		stderrLimitWriter = newOutputLimitWriter(cmd.Stderr, e.maxOutputSize, cancel)
+		cmd.Stdout = stdoutLimitWriter
+		cmd.Stderr = stderrLimitWriter
+	}
+	if err := e.startCmd(cmd); err != nil {
+		return err
+	}
+	err := cmd.Wait()
+	if stopKill != nil {
+		stopKill()
+	}
+	// Wait waits for all output to be copied, so the outputLimitWriters are no longer written to.
+	if stdoutLimitWriter.Exceeded() || stderrLimitWriter.Exceeded() {
+		return NewExitError(
+			exitCodeInternal,
+			NewErrorf(CodeResourceExhausted, "plugin exceeded maximum output size of %d bytes", e.maxOutputSize),
+		)
+	}
+	if err != nil {
 		exitError := &exec.ExitError{}
-		if errors.As(err, &exitError) {
-			return NewExitError(exitError.ExitCode(), exitError)
+		isExitError := errors.As(err, &exitError)
+		exitCode := exitCodeInternal
+		if isExitError {
+			exitCode = exitError.ExitCode()
+			if limitErr := e.getLimitExceededError(exitError.ProcessState); limitErr != nil {
+				return NewExitError(exitCode, limitErr)
+			}
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// The command was cancelled, which is the cause of the error.
+			return NewExitError(exitCode, NewError(codeForContextError(ctxErr), ctxErr))
+		}
+		if isExitError {
+			return NewExitError(exitCode, exitError)
 		}
 		return err
 	}
 	return nil
 }
 
+// getCmdEnv returns the environment variables to run the command with.
+//
+// Later environment variables take precedence, as os/exec uses the last value for
+// duplicate keys.
+func (e *execRunner) getCmdEnv(environ []string) []string {
+	var cmdEnv []string
+	for _, key := range e.envAllowlist {
+		if value, ok := os.LookupEnv(key); ok {
+			cmdEnv = append(cmdEnv, key+"="+value)
+		}
+	}
+	cmdEnv = append(cmdEnv, e.env...)
+	cmdEnv = append(cmdEnv, environ...)
+	if len(cmdEnv) == 0 {
+		return emptyEnv
+	}
+	return cmdEnv
+}
+
+// getCmdDir returns the working directory to run the command in.
+//
+// If both dir and the Runner's dir are empty, this returns the empty string, which results
+// in the current working directory being used.
+func (e *execRunner) getCmdDir(dir string) string {
+	if dir == "" {
+		return e.dir
+	}
+	if e.dir == "" || filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(e.dir, dir)
+}
+
+// outputLimitWriter fails writes once more than a maximum number of bytes are written.
+type outputLimitWriter struct {
+	writer     io.Writer
+	remaining  int64
+	onExceeded func()
+	exceeded   bool
+}
+
+func newOutputLimitWriter(writer io.Writer, maxSize int64, onExceeded func()) *outputLimitWriter {
+	return &outputLimitWriter{
+		writer:     writer,
+		remaining:  maxSize,
+		onExceeded: onExceeded,
+	}
+}
+
+func (o *outputLimitWriter) Write(data []byte) (int, error) {
+	if int64(len(data)) > o.remaining {
+		o.exceeded = true
+		o.onExceeded()
+		return 0, errors.New("maximum output size exceeded")
+	}
+	o.remaining -= int64(len(data))
+	return o.writer.Write(data)
+}
+
+// Exceeded returns true if the maximum size was exceeded.
+//
+// If o is nil, this returns false.
+func (o *outputLimitWriter) Exceeded() bool {
+	return o != nil && o.exceeded
+}
+
 type serverRunner struct {
 	server Server
-	errs   []error
 }
 
 func newServerRunner(server Server) *serverRunner {
@@ -135,11 +418,133 @@ func newServerRunner(server Server) *serverRunner {
 }
 
 func (s *serverRunner) Run(ctx context.Context, env Env) error {
-	if len(s.errs) > 0 {
-		return errors.Join(s.errs...)
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stdin := newServerRunnerStdin(env.Stdin)
+	stdout := newServerRunnerOutput(env.Stdout)
+	stderr := newServerRunnerOutput(env.Stderr)
+	serveEnv := Env{
+		Args:       slices.Clone(env.Args),
+		Environ:    slices.Clone(env.Environ),
+		Dir:        env.Dir,
+		ExtraFiles: slices.Clone(env.ExtraFiles),
+		Stdin:      stdin,
+		Stdout:     stdout,
+		Stderr:     stderr,
 	}
-	// Servers directly return ExitErrors, so this fulfills the contract.
-	return s.server.Serve(ctx, env)
+	done := make(chan error, 1)
+	go func() {
+		// Servers directly return ExitErrors, so this fulfills the contract.
+		err := serveWithPanicRecovery(serveCtx, serveEnv, s.server.Serve)
+		stdout.CloseWrite()
+		stderr.CloseWrite()
+		done <- err
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		ctxErr := ctx.Err()
+		err = NewExitError(exitCodeInternal, NewError(codeForContextError(ctxErr), ctxErr))
+	}
+	// If the Server is still running, this makes any further reads and writes fail.
+	stdin.Close()
+	stdout.Close()
+	stderr.Close()
+	return err
+}
+
+// serveWithPanicRecovery calls serve, converting a panic into an *ExitError wrapping an
+// *Error with CodeInternal.
+func serveWithPanicRecovery(
+	ctx context.Context,
+	env Env,
+	serve func(context.Context, Env) error,
+) (retErr error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			retErr = NewExitError(exitCodeInternal, newPanicError(recovered))
+		}
+	}()
+	return serve(ctx, env)
+}
+
+// serverRunnerStdin is the stdin given to a Server by a serverRunner.
+//
+// Data is copied from the stdin given to Run as the Server reads it.
+type serverRunnerStdin struct {
+	pipeReader *io.PipeReader
+}
+
+func newServerRunnerStdin(stdin io.Reader) *serverRunnerStdin {
+	if stdin == nil {
+		stdin = discardReader{}
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		// If the pipe is closed, the copy stops on the next write.
+		_, err := io.Copy(pipeWriter, stdin)
+		_ = pipeWriter.CloseWithError(err)
+	}()
+	return &serverRunnerStdin{
+		pipeReader: pipeReader,
+	}
+}
+
+func (s *serverRunnerStdin) Read(p []byte) (int, error) {
+	return s.pipeReader.Read(p)
+}
+
+// Close closes the stdin, after which reads by the Server return io.ErrClosedPipe.
+func (s *serverRunnerStdin) Close() {
+	_ = s.pipeReader.Close()
+}
+
+// serverRunnerOutput is the stdout or stderr given to a Server by a serverRunner.
+//
+// Data written by the Server is copied to the stdout or stderr given to Run as it is written.
+type serverRunnerOutput struct {
+	pipeReader *io.PipeReader
+	pipeWriter *io.PipeWriter
+	done       chan struct{}
+}
+
+func newServerRunnerOutput(output io.Writer) *serverRunnerOutput {
+	if output == nil {
+		output = io.Discard
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	serverRunnerOutput := &serverRunnerOutput{
+		pipeReader: pipeReader,
+		pipeWriter: pipeWriter,
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(serverRunnerOutput.done)
+		if _, err := io.Copy(output, pipeReader); err != nil {
+			// Make writes by the Server fail if the output can no longer be written to.
+			_ = pipeReader.CloseWithError(err)
+		}
+	}()
+	return serverRunnerOutput
+}
+
+func (s *serverRunnerOutput) Write(p []byte) (int, error) {
+	return s.pipeWriter.Write(p)
+}
+
+// CloseWrite signals that the Server will not write any more data.
+func (s *serverRunnerOutput) CloseWrite() {
+	_ = s.pipeWriter.Close()
+}
+
+// Close closes the output, after which writes by the Server return io.ErrClosedPipe.
+//
+// This waits for any data already read from the pipe to be written, so that the output
+// given to Run is never written to once Close returns.
+func (s *serverRunnerOutput) Close() {
+	_ = s.pipeReader.Close()
+	<-s.done
 }
 
 type discardReader struct{}
@@ -149,7 +554,16 @@ func (discardReader) Read([]byte) (int, error) {
 }
 
 type execRunnerOptions struct {
-	args []string
+	args             []string
+	env              []string
+	envAllowlist     []string
+	dir              string
+	maxOutputSize    int64
+	memoryLimit      uint64
+	cpuTimeLimit     time.Duration
+	killProcessGroup bool
+	noNewPrivileges  bool
+	gracePeriod      time.Duration
 }
 
 func newExecRunnerOptions() *execRunnerOptions {
@@ -0,0 +1,147 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package pluginrpc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// startCmd starts the command, applying the process group, no_new_privs, and resource
+// limit settings of the execRunner.
+func (e *execRunner) startCmd(cmd *exec.Cmd) error {
+	if e.killProcessGroup {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	var err error
+	if e.noNewPrivileges {
+		err = startCmdWithNoNewPrivileges(cmd)
+	} else {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return err
+	}
+	if err := e.setRlimits(cmd.Process.Pid); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	return nil
+}
+
+// cancelCmd signals the command when its context is done.
+//
+// If there is a grace period, the command is sent SIGTERM, and otherwise SIGKILL. If the
+// process group is to be killed, the entire process group is signalled, and SIGKILL is sent
+// to the process group once the grace period has elapsed. The returned function stops this,
+// and must be called once the command has exited, as the process group ID can then be
+// reused by unrelated processes.
+func (e *execRunner) cancelCmd(cmd *exec.Cmd) (func(), error) {
+	pid := cmd.Process.Pid
+	if e.killProcessGroup {
+		// A negative pid signals the process group, which has the pid of the process as its ID.
+		pid = -pid
+	}
+	signal := syscall.SIGKILL
+	var stopKill func()
+	if e.gracePeriod > 0 {
+		signal = syscall.SIGTERM
+		if e.killProcessGroup {
+			// os/exec only kills the process itself once the grace period has elapsed, so
+			// we kill the process group ourselves.
+			timer := time.AfterFunc(e.gracePeriod, func() {
+				_ = syscall.Kill(pid, syscall.SIGKILL)
+			})
+			stopKill = func() { timer.Stop() }
+		}
+	}
+	if err := syscall.Kill(pid, signal); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return stopKill, os.ErrProcessDone
+		}
+		return stopKill, err
+	}
+	return stopKill, nil
+}
+
+// setRlimits sets the resource limits of the process with the given pid.
+//
+// Resource limits are a property of a process rather than a thread, so they cannot be set
+// between fork and exec by os/exec, and are instead set as soon as the process has started.
+func (e *execRunner) setRlimits(pid int) error {
+	if e.memoryLimit > 0 {
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, &unix.Rlimit{Cur: e.memoryLimit, Max: e.memoryLimit}, nil); err != nil {
+			return err
+		}
+	}
+	if e.cpuTimeLimit > 0 {
+		seconds := e.getCPUTimeLimitSeconds()
+		// The process receives SIGXCPU at the soft limit, and SIGKILL at the hard limit. Some
+		// programs, including Go programs, ignore SIGXCPU, so we make sure they are killed.
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: seconds, Max: seconds + 1}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLimitExceededError returns an *Error with CodeResourceExhausted if the process was
+// killed for exceeding its CPU time limit, and nil otherwise.
+func (e *execRunner) getLimitExceededError(processState *os.ProcessState) *Error {
+	if e.cpuTimeLimit <= 0 || processState == nil {
+		return nil
+	}
+	waitStatus, ok := processState.Sys().(syscall.WaitStatus)
+	if !ok || !waitStatus.Signaled() {
+		return nil
+	}
+	cpuTimeLimit := time.Duration(e.getCPUTimeLimitSeconds()) * time.Second
+	if processState.UserTime()+processState.SystemTime() < cpuTimeLimit {
+		return nil
+	}
+	return NewErrorf(CodeResourceExhausted, "plugin exceeded CPU time limit of %v", cpuTimeLimit)
+}
+
+func (e *execRunner) getCPUTimeLimitSeconds() uint64 {
+	return uint64((e.cpuTimeLimit + time.Second - 1) / time.Second)
+}
+
+// startCmdWithNoNewPrivileges starts the command with the no_new_privs attribute set.
+//
+// no_new_privs is a per-thread attribute that is inherited by child processes. We set it on
+// a dedicated thread that we start the command from, and then never unlock the thread, so that
+// the thread is terminated when the goroutine exits, and no other goroutine runs with
+// no_new_privs set.
+func startCmdWithNoNewPrivileges(cmd *exec.Cmd) error {
+	errC := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			errC <- err
+			return
+		}
+		errC <- cmd.Start()
+	}()
+	return <-errC
+}
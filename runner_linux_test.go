@@ -0,0 +1,306 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package pluginrpc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+// misbehaveEnvKey is the environment variable that, if set, results in the test binary acting
+// as a misbehaving plugin instead of running tests.
+const misbehaveEnvKey = "PLUGINRPC_TEST_MISBEHAVE"
+
+func TestMain(m *testing.M) {
+	if misbehavior := os.Getenv(misbehaveEnvKey); misbehavior != "" {
+		if err := misbehave(misbehavior); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestExecRunnerMaxOutputSize(t *testing.T) {
+	t.Parallel()
+	err := newMisbehavingRunner(t, "output", pluginrpc.ExecRunnerWithMaxOutputSize(1024)).Run(
+		context.Background(),
+		pluginrpc.Env{},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeResourceExhausted, pluginrpcError.Code())
+}
+
+func TestExecRunnerCPUTimeLimit(t *testing.T) {
+	t.Parallel()
+	err := newMisbehavingRunner(t, "cpu", pluginrpc.ExecRunnerWithCPUTimeLimit(time.Second)).Run(
+		context.Background(),
+		pluginrpc.Env{},
+	)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeResourceExhausted, pluginrpcError.Code())
+}
+
+func TestExecRunnerMemoryLimit(t *testing.T) {
+	t.Parallel()
+	err := newMisbehavingRunner(t, "memory", pluginrpc.ExecRunnerWithMemoryLimit(1<<30)).Run(
+		context.Background(),
+		pluginrpc.Env{},
+	)
+	exitError := &pluginrpc.ExitError{}
+	require.ErrorAs(t, err, &exitError)
+}
+
+func TestExecRunnerLimits(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		newMisbehavingRunner(
+			t,
+			"rlimits",
+			pluginrpc.ExecRunnerWithMemoryLimit(1<<32),
+			pluginrpc.ExecRunnerWithCPUTimeLimit(1500*time.Millisecond),
+		).Run(
+			context.Background(),
+			pluginrpc.Env{Stdout: stdout},
+		),
+	)
+	require.Equal(t, "as=4294967296:4294967296 cpu=2:3", stdout.String())
+}
+
+func TestExecRunnerKillProcessGroup(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The plugin writes the pid of its child to stdout, and then blocks.
+	stdout := newNotifyingBuffer()
+	runner := newMisbehavingRunner(t, "spawn", pluginrpc.ExecRunnerWithKillProcessGroup())
+	runErrC := make(chan error, 1)
+	go func() {
+		runErrC <- runner.Run(ctx, pluginrpc.Env{Stdout: stdout})
+	}()
+	<-stdout.written
+	pid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	require.NoError(t, err)
+	cancel()
+	require.Error(t, <-runErrC)
+	require.Eventually(t, func() bool { return !isProcessRunning(pid) }, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestExecRunnerNoNewPrivileges(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		newMisbehavingRunner(t, "no-new-privs", pluginrpc.ExecRunnerWithNoNewPrivileges()).Run(
+			context.Background(),
+			pluginrpc.Env{Stdout: stdout},
+		),
+	)
+	require.Equal(t, "1", stdout.String())
+}
+
+func TestExecRunnerGracePeriod(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The plugin writes to stdout once it is ready, and again once it receives SIGTERM.
+	stdout := newNotifyingBuffer()
+	runner := newMisbehavingRunner(t, "trap", pluginrpc.ExecRunnerWithGracePeriod(10*time.Second))
+	runErrC := make(chan error, 1)
+	go func() {
+		runErrC <- runner.Run(ctx, pluginrpc.Env{Stdout: stdout})
+	}()
+	<-stdout.written
+	cancel()
+	err := <-runErrC
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeCanceled, pluginrpcError.Code())
+	require.Equal(t, "ready\ncleaned up\n", stdout.String())
+}
+
+func TestExecRunnerGracePeriodExceeded(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := newMisbehavingRunner(t, "ignore-sigterm", pluginrpc.ExecRunnerWithGracePeriod(time.Second)).Run(
+		ctx,
+		pluginrpc.Env{},
+	)
+	// The plugin ignores SIGTERM, so it is only killed once the grace period has elapsed.
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+	pluginrpcError := &pluginrpc.Error{}
+	require.ErrorAs(t, err, &pluginrpcError)
+	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
+}
+
+func newMisbehavingRunner(t *testing.T, misbehavior string, options ...pluginrpc.ExecRunnerOption) pluginrpc.Runner {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	return pluginrpc.NewExecRunner(
+		executable,
+		append(
+			[]pluginrpc.ExecRunnerOption{
+				pluginrpc.ExecRunnerWithEnv(misbehaveEnvKey + "=" + misbehavior),
+			},
+			options...,
+		)...,
+	)
+}
+
+func misbehave(misbehavior string) error {
+	switch misbehavior {
+	case "output":
+		data := bytes.Repeat([]byte("a"), 1024)
+		for {
+			if _, err := os.Stdout.Write(data); err != nil {
+				return err
+			}
+		}
+	case "cpu":
+		for {
+		}
+	case "memory":
+		data := make([]byte, 2<<30)
+		for i := range data {
+			data[i] = 1
+		}
+		return nil
+	case "spawn":
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command(executable)
+		cmd.Env = []string{misbehaveEnvKey + "=sleep"}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		if _, err := fmt.Println(cmd.Process.Pid); err != nil {
+			return err
+		}
+		return cmd.Wait()
+	case "trap":
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+		defer stop()
+		if _, err := fmt.Println("ready"); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		_, err := fmt.Println("cleaned up")
+		return err
+	case "ignore-sigterm":
+		signal.Ignore(syscall.SIGTERM)
+		time.Sleep(time.Hour)
+		return nil
+	case "sleep":
+		time.Sleep(time.Hour)
+		return nil
+	case "rlimits":
+		// The limits are applied right after the process starts, so we wait for both of them
+		// to be applied.
+		var asRlimit, cpuRlimit syscall.Rlimit
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if err := syscall.Getrlimit(syscall.RLIMIT_AS, &asRlimit); err != nil {
+				return err
+			}
+			if err := syscall.Getrlimit(syscall.RLIMIT_CPU, &cpuRlimit); err != nil {
+				return err
+			}
+			if asRlimit.Max == 1<<32 && cpuRlimit.Max == 3 {
+				break
+			}
+		}
+		_, err := fmt.Printf("as=%d:%d cpu=%d:%d", asRlimit.Cur, asRlimit.Max, cpuRlimit.Cur, cpuRlimit.Max)
+		return err
+	case "no-new-privs":
+		data, err := os.ReadFile("/proc/self/status")
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if value, ok := strings.CutPrefix(line, "NoNewPrivs:"); ok {
+				_, err := fmt.Print(strings.TrimSpace(value))
+				return err
+			}
+		}
+		return errors.New("NoNewPrivs not found in /proc/self/status")
+	default:
+		return fmt.Errorf("unknown misbehavior %q", misbehavior)
+	}
+}
+
+// isProcessRunning returns true if the process with the given pid exists and is not a zombie.
+func isProcessRunning(pid int) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is in parentheses.
+	index := bytes.LastIndexByte(data, ')')
+	return index < 0 || !bytes.HasPrefix(data[index+1:], []byte(" Z"))
+}
+
+// notifyingBuffer is a buffer that closes written on the first write.
+//
+// This does not embed bytes.Buffer, so that io.Copy calls Write rather than ReadFrom.
+type notifyingBuffer struct {
+	buffer  bytes.Buffer
+	written chan struct{}
+	lock    sync.Mutex
+}
+
+func newNotifyingBuffer() *notifyingBuffer {
+	return &notifyingBuffer{
+		written: make(chan struct{}),
+	}
+}
+
+func (n *notifyingBuffer) Write(data []byte) (int, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.buffer.Len() == 0 && len(data) > 0 {
+		defer close(n.written)
+	}
+	return n.buffer.Write(data)
+}
+
+func (n *notifyingBuffer) String() string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.buffer.String()
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package pluginrpc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// startCmd starts the command.
+//
+// Process groups, no_new_privs, and resource limits are only supported on Linux.
+func (e *execRunner) startCmd(cmd *exec.Cmd) error {
+	if e.killProcessGroup || e.noNewPrivileges || e.memoryLimit > 0 || e.cpuTimeLimit > 0 {
+		return errors.New("process groups, no_new_privs, and resource limits are only supported on Linux")
+	}
+	return cmd.Start()
+}
+
+// cancelCmd signals the command when its context is done.
+//
+// If there is a grace period, the command is sent SIGTERM, and otherwise killed.
+//
+// This never schedules a kill, so the returned function is always nil.
+func (e *execRunner) cancelCmd(cmd *exec.Cmd) (func(), error) {
+	if e.gracePeriod <= 0 {
+		return nil, cmd.Process.Kill()
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		// Windows does not support sending signals other than kill.
+		return nil, cmd.Process.Kill()
+	}
+	return nil, nil
+}
+
+// getLimitExceededError returns nil, as resource limits are only supported on Linux.
+func (*execRunner) getLimitExceededError(*os.ProcessState) *Error {
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// RunnerRegistry maps plugin names to Runners.
+//
+// Hosts can use a RunnerRegistry to run plugins that are compiled into the host in-process,
+// and all other plugins as external programs. Code that calls plugins looks up the Runner by
+// name, and does not need to change if a plugin switches between the two.
+type RunnerRegistry interface {
+	// RegisterServer registers the given Server as the plugin with the given name.
+	//
+	// The plugin is run in-process via NewServerRunner.
+	// Names must be non-empty and unique.
+	RegisterServer(name string, server Server, options ...ServerRunnerOption) error
+	// RegisterProgram registers the given program as the plugin with the given name.
+	//
+	// The plugin is run as an external program via NewExecRunner.
+	// Names must be non-empty and unique.
+	RegisterProgram(name string, programName string, options ...ExecRunnerOption) error
+	// Runner returns the Runner for the plugin with the given name.
+	//
+	// If no plugin is registered with the given name, this returns nil.
+	Runner(name string) Runner
+	// Names returns the names of all registered plugins, in sorted order.
+	Names() []string
+
+	isRunnerRegistry()
+}
+
+// NewRunnerRegistry returns a new RunnerRegistry.
+func NewRunnerRegistry() RunnerRegistry {
+	return newRunnerRegistry()
+}
+
+// *** PRIVATE ***
+
+type runnerRegistry struct {
+	nameToRunner map[string]Runner
+	lock         sync.RWMutex
+}
+
+func newRunnerRegistry() *runnerRegistry {
+	return &runnerRegistry{
+		nameToRunner: make(map[string]Runner),
+	}
+}
+
+func (r *runnerRegistry) RegisterServer(name string, server Server, options ...ServerRunnerOption) error {
+	return r.register(name, NewServerRunner(server, options...))
+}
+
+func (r *runnerRegistry) RegisterProgram(name string, programName string, options ...ExecRunnerOption) error {
+	return r.register(name, NewExecRunner(programName, options...))
+}
+
+func (r *runnerRegistry) Runner(name string) Runner {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.nameToRunner[name]
+}
+
+func (r *runnerRegistry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.nameToRunner))
+	for name := range r.nameToRunner {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (*runnerRegistry) isRunnerRegistry() {}
+
+func (r *runnerRegistry) register(name string, runner Runner) error {
+	if name == "" {
+		return errors.New("plugin name is empty")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.nameToRunner[name]; ok {
+		return fmt.Errorf("plugin %q already registered", name)
+	}
+	r.nameToRunner[name] = runner
+	return nil
+}
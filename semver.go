@@ -0,0 +1,112 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semverRegexp matches semantic versions as per https://semver.org, optionally prefixed
+// with a "v".
+var semverRegexp = regexp.MustCompile(
+	`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`,
+)
+
+// semver is a parsed semantic version.
+type semver struct {
+	major      uint64
+	minor      uint64
+	patch      uint64
+	prerelease []string
+}
+
+func parseSemver(version string) (*semver, error) {
+	matches := semverRegexp.FindStringSubmatch(version)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid semantic version: %q", version)
+	}
+	numbers := make([]uint64, 3)
+	for i := range numbers {
+		number, err := strconv.ParseUint(matches[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version: %q: %w", version, err)
+		}
+		numbers[i] = number
+	}
+	semver := &semver{
+		major: numbers[0],
+		minor: numbers[1],
+		patch: numbers[2],
+	}
+	if matches[4] != "" {
+		semver.prerelease = strings.Split(matches[4], ".")
+	}
+	return semver, nil
+}
+
+// compare compares the precedence of two semantic versions, returning -1, 0, or 1.
+//
+// Build metadata does not affect precedence.
+func (s *semver) compare(other *semver) int {
+	if c := cmp.Compare(s.major, other.major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(s.minor, other.minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(s.patch, other.patch); c != 0 {
+		return c
+	}
+	// A version without a prerelease has a higher precedence than one with a prerelease.
+	switch {
+	case len(s.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(s.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(s.prerelease) && i < len(other.prerelease); i++ {
+		if c := comparePrereleaseIdentifiers(s.prerelease[i], other.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(s.prerelease), len(other.prerelease))
+}
+
+// comparePrereleaseIdentifiers compares two prerelease identifiers.
+//
+// Numeric identifiers are compared numerically, and have a lower precedence than
+// alphanumeric identifiers, which are compared lexically.
+func comparePrereleaseIdentifiers(a string, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(aNumber, bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
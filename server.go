@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"slices"
 	"strconv"
+	"strings"
 )
 
 // Server is the server for plugin implementations.
@@ -59,6 +60,7 @@ func ServerWithFlagPrefix(flagPrefix string) ServerOption {
 
 type server struct {
 	spec            Spec
+	specID          string
 	flagPrefix      string
 	pathToServeFunc map[string]func(context.Context, Env) error
 }
@@ -83,37 +85,78 @@ func newServer(spec Spec, serverRegistrar ServerRegistrar, options ...ServerOpti
 			return nil, fmt.Errorf("path %q not registered", procedure.Path())
 		}
 	}
+	specID, err := newSpecID(spec)
+	if err != nil {
+		return nil, err
+	}
 	return &server{
 		spec:            spec,
+		specID:          specID,
 		flagPrefix:      serverOptions.flagPrefix,
 		pathToServeFunc: pathToServeFunc,
 	}, nil
 }
 
 func (s *server) Serve(ctx context.Context, env Env) error {
+	if len(env.Args) == 1 && env.Args[0] == fullFlag(s.flagPrefix, flagServeSuffix) {
+		return serveSession(ctx, env, s.serveOnce)
+	}
+	return s.serveOnce(ctx, env)
+}
+
+// serveOnce serves a single invocation of the plugin.
+func (s *server) serveOnce(ctx context.Context, env Env) error {
 	if len(env.Args) == 1 {
 		if env.Args[0] == fullFlag(s.flagPrefix, flagProtocolSuffix) {
-			_, err := env.Stdout.Write([]byte(strconv.Itoa(protocolVersion) + "\n"))
-			return err
-		}
-		if env.Args[0] == fullFlag(s.flagPrefix, flagSpecSuffix) {
-			data, err := marshalFlag(NewProtoSpec(s.spec))
+			data, err := readStdin(env.Stdin)
+			if err != nil {
+				return err
+			}
+			version, err := negotiateProtocolVersion(data)
 			if err != nil {
 				return err
 			}
-			_, err = env.Stdout.Write(append(data, []byte("\n")...))
+			_, err = env.Stdout.Write([]byte(strconv.Itoa(version) + "\n"))
 			return err
 		}
+		if env.Args[0] == fullFlag(s.flagPrefix, flagSpecSuffix) {
+			return s.serveSpec(env)
+		}
+		if env.Args[0] == fullFlag(s.flagPrefix, flagReflectSuffix) {
+			return s.serveReflection(env)
+		}
+	}
+	if len(env.Args) >= 2 && env.Args[0] == fullFlag(s.flagPrefix, flagSpecIDSuffix) {
+		if env.Args[1] != s.specID {
+			return s.writeSpecIDMismatch(env, env.Args[1])
+		}
+		env.Args = env.Args[2:]
 	}
+	ctx = withEnviron(ctx, env.Environ)
 	for _, procedure := range s.spec.Procedures() {
-		if slices.Equal(env.Args, []string{procedure.Path()}) {
-			serveFunc := s.pathToServeFunc[procedure.Path()]
-			return serveFunc(ctx, env)
+		flagArgs, ok := getProcedureFlagArgs(procedure, env.Args)
+		if !ok {
+			continue
+		}
+		if slices.ContainsFunc(flagArgs, isHelpFlag) {
+			return writeProcedureHelp(env.Stdout, procedure, s.flagPrefix)
+		}
+		cliOptions, err := parseCLIOptions(s.flagPrefix, flagArgs)
+		if err != nil {
+			return err
 		}
-		// TODO: Make sure args do not overlap in procedures
-		if slices.Equal(env.Args, procedure.Args()) {
-			serveFunc := s.pathToServeFunc[procedure.Path()]
-			return serveFunc(ctx, env)
+		serveFunc := s.pathToServeFunc[procedure.Path()]
+		return serveFunc(withCLIOptions(withProcedurePath(ctx, procedure.Path()), cliOptions), env)
+	}
+	// Clients never invoke plugins without args, so the plugin was invoked by a user.
+	if len(env.Args) == 0 || (len(env.Args) == 1 && isHelpFlag(env.Args[0])) {
+		return writeHelp(env.Stdout, s.spec)
+	}
+	// The args do not match anything, so the plugin was likely invoked by a user rather
+	// than a client. Print the help so that the user knows what is available.
+	if env.Stderr != nil {
+		if err := writeHelp(env.Stderr, s.spec); err != nil {
+			return err
 		}
 	}
 	return fmt.Errorf("args not recognized: %v", env.Args)
@@ -121,6 +164,112 @@ func (s *server) Serve(ctx context.Context, env Env) error {
 
 func (*server) isServer() {}
 
+// serveSpec writes the Spec to stdout.
+//
+// If the client sent a header frame, the spec ID and the other properties of the Spec that
+// pluginrpcv1beta1.Spec cannot represent are sent in a header frame first.
+func (s *server) serveSpec(env Env) error {
+	stdinData, err := readStdin(env.Stdin)
+	if err != nil {
+		return err
+	}
+	requestHeader, _, err := decodeHeaderFrame(stdinData)
+	if err != nil {
+		return err
+	}
+	if requestHeader != nil {
+		headerFrame, err := encodeHeaderFrame(newSpecHeader(s.spec, s.specID))
+		if err != nil {
+			return err
+		}
+		if _, err := env.Stdout.Write(headerFrame); err != nil {
+			return err
+		}
+	}
+	data, err := marshalFlag(NewProtoSpec(s.spec))
+	if err != nil {
+		return err
+	}
+	_, err = env.Stdout.Write(append(data, []byte("\n")...))
+	return err
+}
+
+// serveReflection writes a FileDescriptorSet containing the descriptors of the Procedures
+// to stdout.
+//
+// The FileDescriptorSet is written as JSON, in the same way as the Spec.
+func (s *server) serveReflection(env Env) error {
+	data, err := marshalFlag(newFileDescriptorSet(s.spec))
+	if err != nil {
+		return err
+	}
+	_, err = env.Stdout.Write(append(data, []byte("\n")...))
+	return err
+}
+
+// writeSpecIDMismatch writes a header frame with the current spec ID, followed by an error.
+//
+// Clients detect the mismatch via the header frame. The error is written as JSON, so that
+// the error is readable when the plugin is invoked as a CLI.
+func (s *server) writeSpecIDMismatch(env Env, specID string) error {
+	if err := s.writeSpecIDHeader(env); err != nil {
+		return err
+	}
+	data, err := marshalResponse(
+		formatJSON,
+		nil,
+		NewErrorf(CodeFailedPrecondition, "spec ID %q does not match current spec ID %q", specID, s.specID),
+	)
+	if err != nil {
+		return err
+	}
+	return writeFrame(env.Stdout, formatJSON, data)
+}
+
+func (s *server) writeSpecIDHeader(env Env) error {
+	headerFrame, err := encodeHeaderFrame(Header{headerKeySpecID: []string{s.specID}})
+	if err != nil {
+		return err
+	}
+	_, err = env.Stdout.Write(headerFrame)
+	return err
+}
+
+// getProcedureFlagArgs returns the args that follow the args of the Procedure, if the
+// given args invoke the Procedure.
+//
+// A Procedure is invoked with either its path or its args, followed by any number of flags.
+// Procedures without args are only invoked via their path, so that invoking the plugin
+// without any args does not invoke a Procedure.
+func getProcedureFlagArgs(procedure Procedure, args []string) ([]string, bool) {
+	// TODO: Make sure args do not overlap in procedures
+	for _, procedureArgs := range [][]string{{procedure.Path()}, procedure.Args()} {
+		if len(procedureArgs) == 0 || len(args) < len(procedureArgs) || !slices.Equal(args[:len(procedureArgs)], procedureArgs) {
+			continue
+		}
+		flagArgs := args[len(procedureArgs):]
+		if slices.ContainsFunc(flagArgs, func(arg string) bool { return !strings.HasPrefix(arg, "-") }) {
+			continue
+		}
+		return flagArgs, true
+	}
+	return nil, false
+}
+
+type procedurePathContextKey struct{}
+
+// withProcedurePath returns a context with the path of the Procedure being served.
+func withProcedurePath(ctx context.Context, procedurePath string) context.Context {
+	return context.WithValue(ctx, procedurePathContextKey{}, procedurePath)
+}
+
+// procedurePathFromContext returns the path of the Procedure being served, or the empty
+// string if the context was not created by a Server.
+func procedurePathFromContext(ctx context.Context) string {
+	procedurePath, _ := ctx.Value(procedurePathContextKey{}).(string)
+	return procedurePath
+}
+
 type serverOptions struct {
 	flagPrefix string
 }
@@ -0,0 +1,543 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	sessionv1 "github.com/bufbuild/pluginrpc-go/internal/gen/buf/pluginrpc/session/v1"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// A session is a long-lived plugin process invoked with `--plugin-serve`, over which
+// many calls are multiplexed.
+//
+// Clients send size-delimited SessionRequests on the stdin of the plugin, and the plugin
+// sends size-delimited SessionResponses on stdout. Each call is identified by an ID chosen
+// by the client. A call is the equivalent of a single invocation of the plugin: it has args
+// and stdin, produces stdout and stderr, and completes with an exit code.
+
+// sessionCloseTimeout is how long Close waits for the plugin process to exit after its
+// stdin is closed, before the plugin process is cancelled.
+const sessionCloseTimeout = time.Minute
+
+var errSessionClosed = errors.New("plugin session closed")
+
+// *** CLIENT ***
+
+// sessionRunner is a Runner that multiplexes calls over a single plugin process.
+type sessionRunner struct {
+	stdin  io.WriteCloser
+	cancel context.CancelFunc
+	// done is closed once both the plugin process has exited and all responses have been read.
+	done chan struct{}
+
+	writeLock sync.Mutex
+
+	lock     sync.Mutex
+	nextID   uint64
+	idToCall map[uint64]*sessionCall
+	err      error
+}
+
+// newSessionRunner starts a new plugin process with the given args using the given
+// Runner, and returns a sessionRunner that multiplexes calls over the process.
+func newSessionRunner(runner Runner, args []string, stderr io.Writer) *sessionRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	sessionRunner := &sessionRunner{
+		stdin:    stdinWriter,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		idToCall: make(map[uint64]*sessionCall),
+	}
+	runDone := make(chan struct{})
+	go func() {
+		defer close(runDone)
+		err := runner.Run(
+			ctx,
+			Env{
+				Args:   args,
+				Stdin:  stdinReader,
+				Stdout: stdoutWriter,
+				Stderr: stderr,
+			},
+		)
+		// Unblock any pending writes to the plugin process.
+		_ = stdinReader.CloseWithError(errSessionClosed)
+		if err != nil {
+			_ = stdoutWriter.CloseWithError(fmt.Errorf("%w: %w", errSessionClosed, err))
+			return
+		}
+		_ = stdoutWriter.CloseWithError(errSessionClosed)
+	}()
+	go func() {
+		defer close(sessionRunner.done)
+		sessionRunner.readLoop(stdoutReader)
+		<-runDone
+	}()
+	return sessionRunner
+}
+
+func (s *sessionRunner) Run(ctx context.Context, env Env) error {
+	var stdin []byte
+	if env.Stdin != nil {
+		data, err := io.ReadAll(env.Stdin)
+		if err != nil {
+			return err
+		}
+		stdin = data
+	}
+	call := newSessionCall(env.Stdout, env.Stderr)
+	id, err := s.register(call)
+	if err != nil {
+		return err
+	}
+	go call.flush()
+	if err := s.send(
+		&sessionv1.SessionRequest{
+			Id: id,
+			Value: &sessionv1.SessionRequest_Start_{
+				Start: &sessionv1.SessionRequest_Start{
+					Args:    env.Args,
+					Stdin:   stdin,
+					Environ: env.Environ,
+				},
+			},
+		},
+	); err != nil {
+		s.unregister(id)
+		call.abandon(err)
+		return err
+	}
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		s.unregister(id)
+		call.abandon(ctx.Err())
+		// This is best-effort, the plugin process may have already exited.
+		_ = s.send(
+			&sessionv1.SessionRequest{
+				Id: id,
+				Value: &sessionv1.SessionRequest_Cancel_{
+					Cancel: &sessionv1.SessionRequest_Cancel{},
+				},
+			},
+		)
+		return ctx.Err()
+	}
+}
+
+// Closed returns true if the plugin process has exited.
+func (s *sessionRunner) Closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the stdin of the plugin process, and waits for the plugin process to exit.
+//
+// The plugin process will complete any in-flight calls before exiting. If the plugin
+// process has not exited within sessionCloseTimeout, it is cancelled.
+func (s *sessionRunner) Close() error {
+	err := s.stdin.Close()
+	timer := time.NewTimer(sessionCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+	case <-timer.C:
+		s.cancel()
+		<-s.done
+	}
+	s.cancel()
+	return err
+}
+
+func (s *sessionRunner) register(call *sessionCall) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	id := s.nextID
+	s.nextID++
+	s.idToCall[id] = call
+	return id, nil
+}
+
+func (s *sessionRunner) unregister(id uint64) *sessionCall {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	call, ok := s.idToCall[id]
+	if !ok {
+		return nil
+	}
+	delete(s.idToCall, id)
+	return call
+}
+
+func (s *sessionRunner) get(id uint64) *sessionCall {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.idToCall[id]
+}
+
+func (s *sessionRunner) send(sessionRequest *sessionv1.SessionRequest) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	_, err := protodelim.MarshalTo(s.stdin, sessionRequest)
+	return err
+}
+
+func (s *sessionRunner) readLoop(reader *io.PipeReader) {
+	err := s.readAll(bufio.NewReader(reader))
+	// Nothing reads stdout from now on, so unblock any pending writes from the plugin process.
+	_ = reader.CloseWithError(err)
+	if !errors.Is(err, errSessionClosed) {
+		// The plugin process sent something that is not a SessionResponse, and cannot be
+		// relied on to exit once its stdin is closed.
+		s.cancel()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+	for id, call := range s.idToCall {
+		call.complete(err)
+		delete(s.idToCall, id)
+	}
+}
+
+// readAll reads all SessionResponses from the plugin process, returning the error
+// that terminated the session. This will never return nil.
+func (s *sessionRunner) readAll(reader *bufio.Reader) error {
+	for {
+		sessionResponse := &sessionv1.SessionResponse{}
+		if err := protodelim.UnmarshalFrom(reader, sessionResponse); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errSessionClosed
+			}
+			return err
+		}
+		id := sessionResponse.GetId()
+		switch value := sessionResponse.GetValue().(type) {
+		case *sessionv1.SessionResponse_Stdout:
+			if call := s.get(id); call != nil {
+				call.writeStdout(value.Stdout)
+			}
+		case *sessionv1.SessionResponse_Stderr:
+			if call := s.get(id); call != nil {
+				call.writeStderr(value.Stderr)
+			}
+		case *sessionv1.SessionResponse_Exit_:
+			if call := s.unregister(id); call != nil {
+				call.complete(exitToError(value.Exit))
+			}
+		default:
+			return fmt.Errorf("unknown session response type %T", value)
+		}
+	}
+}
+
+// sessionCall is a single call within a session on the client side.
+//
+// The output of a call is buffered, and written to stdout and stderr by a separate goroutine
+// (see flush), so that the read loop of the session never blocks on a caller that does not
+// drain its output. Otherwise, one such caller would block every other call in the session.
+type sessionCall struct {
+	stdout io.Writer
+	stderr io.Writer
+	// signal is notified whenever chunks or completed changes.
+	signal chan struct{}
+	// done is closed once the call has completed and all of its output has been written.
+	done chan struct{}
+
+	// lock protects chunks, completed, and err.
+	lock      sync.Mutex
+	chunks    []sessionCallChunk
+	completed bool
+	err       error
+}
+
+// sessionCallChunk is a chunk of the output of a call.
+type sessionCallChunk struct {
+	data   []byte
+	stderr bool
+}
+
+func newSessionCall(stdout io.Writer, stderr io.Writer) *sessionCall {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	return &sessionCall{
+		stdout: stdout,
+		stderr: stderr,
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *sessionCall) writeStdout(data []byte) {
+	s.write(sessionCallChunk{data: data})
+}
+
+func (s *sessionCall) writeStderr(data []byte) {
+	s.write(sessionCallChunk{data: data, stderr: true})
+}
+
+// complete completes the call with the given error, once all buffered output is written.
+func (s *sessionCall) complete(err error) {
+	s.lock.Lock()
+	if !s.completed {
+		s.completed = true
+		s.err = err
+	}
+	s.lock.Unlock()
+	s.notify()
+}
+
+// abandon completes the call with the given error, discarding any buffered output.
+//
+// This is used when the caller of the call has gone away, and no longer waits on done.
+func (s *sessionCall) abandon(err error) {
+	s.lock.Lock()
+	if !s.completed {
+		s.completed = true
+		s.err = err
+	}
+	s.chunks = nil
+	s.lock.Unlock()
+	s.notify()
+}
+
+// flush writes the buffered output of the call until the call has completed, and then
+// closes done. This must be run in its own goroutine.
+func (s *sessionCall) flush() {
+	for {
+		s.lock.Lock()
+		chunks := s.chunks
+		s.chunks = nil
+		completed := s.completed
+		s.lock.Unlock()
+		for _, chunk := range chunks {
+			if chunk.stderr {
+				_, _ = s.stderr.Write(chunk.data)
+			} else {
+				_, _ = s.stdout.Write(chunk.data)
+			}
+		}
+		if len(chunks) > 0 {
+			continue
+		}
+		if completed {
+			close(s.done)
+			return
+		}
+		<-s.signal
+	}
+}
+
+func (s *sessionCall) write(chunk sessionCallChunk) {
+	s.lock.Lock()
+	if s.completed {
+		s.lock.Unlock()
+		return
+	}
+	s.chunks = append(s.chunks, chunk)
+	s.lock.Unlock()
+	s.notify()
+}
+
+func (s *sessionCall) notify() {
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// *** SERVER ***
+
+// serverSession manages the calls within a session on the server side.
+type serverSession struct {
+	stdout io.Writer
+	// environ are the environment variables of the plugin process, which every call inherits.
+	environ   []string
+	writeLock sync.Mutex
+
+	lock       sync.Mutex
+	idToCancel map[uint64]context.CancelFunc
+	waitGroup  sync.WaitGroup
+}
+
+// serveSession reads SessionRequests from stdin until stdin is closed, and serves
+// each call using the given function.
+//
+// All calls are served concurrently. Once stdin is closed, serveSession waits for all
+// in-flight calls to complete before returning.
+func serveSession(ctx context.Context, env Env, serve func(context.Context, Env) error) error {
+	serverSession := &serverSession{
+		stdout:     env.Stdout,
+		environ:    env.Environ,
+		idToCancel: make(map[uint64]context.CancelFunc),
+	}
+	defer serverSession.waitGroup.Wait()
+	if env.Stdin == nil {
+		return nil
+	}
+	reader := bufio.NewReader(env.Stdin)
+	for {
+		sessionRequest := &sessionv1.SessionRequest{}
+		if err := protodelim.UnmarshalFrom(reader, sessionRequest); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		id := sessionRequest.GetId()
+		switch value := sessionRequest.GetValue().(type) {
+		case *sessionv1.SessionRequest_Start_:
+			serverSession.start(ctx, id, value.Start, serve)
+		case *sessionv1.SessionRequest_Cancel_:
+			serverSession.cancel(id)
+		default:
+			return fmt.Errorf("unknown session request type %T", value)
+		}
+	}
+}
+
+func (s *serverSession) start(
+	ctx context.Context,
+	id uint64,
+	start *sessionv1.SessionRequest_Start,
+	serve func(context.Context, Env) error,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.lock.Lock()
+	if _, ok := s.idToCancel[id]; ok {
+		s.lock.Unlock()
+		cancel()
+		s.writeExit(id, fmt.Errorf("duplicate session call ID: %d", id))
+		return
+	}
+	s.idToCancel[id] = cancel
+	s.lock.Unlock()
+
+	s.waitGroup.Add(1)
+	go func() {
+		defer s.waitGroup.Done()
+		err := serveWithPanicRecovery(
+			ctx,
+			Env{
+				Args:    start.GetArgs(),
+				Environ: append(slices.Clone(s.environ), start.GetEnviron()...),
+				Stdin:   bytes.NewReader(start.GetStdin()),
+				Stdout:  &sessionWriter{serverSession: s, id: id},
+				Stderr:  &sessionWriter{serverSession: s, id: id, stderr: true},
+			},
+			serve,
+		)
+		s.lock.Lock()
+		delete(s.idToCancel, id)
+		s.lock.Unlock()
+		cancel()
+		s.writeExit(id, err)
+	}()
+}
+
+func (s *serverSession) cancel(id uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cancel, ok := s.idToCancel[id]; ok {
+		cancel()
+	}
+}
+
+func (s *serverSession) writeExit(id uint64, err error) {
+	// If we cannot write to stdout, the client has gone away, and there is nothing to do.
+	_ = s.send(
+		&sessionv1.SessionResponse{
+			Id: id,
+			Value: &sessionv1.SessionResponse_Exit_{
+				Exit: errorToExit(err),
+			},
+		},
+	)
+}
+
+func (s *serverSession) send(sessionResponse *sessionv1.SessionResponse) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	_, err := protodelim.MarshalTo(s.stdout, sessionResponse)
+	return err
+}
+
+// sessionWriter writes stdout or stderr for a single call within a session.
+type sessionWriter struct {
+	serverSession *serverSession
+	id            uint64
+	stderr        bool
+}
+
+func (s *sessionWriter) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	sessionResponse := &sessionv1.SessionResponse{
+		Id: s.id,
+	}
+	// Copy the data, as the caller may reuse the slice.
+	data = bytes.Clone(data)
+	if s.stderr {
+		sessionResponse.Value = &sessionv1.SessionResponse_Stderr{Stderr: data}
+	} else {
+		sessionResponse.Value = &sessionv1.SessionResponse_Stdout{Stdout: data}
+	}
+	if err := s.serverSession.send(sessionResponse); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func errorToExit(err error) *sessionv1.SessionResponse_Exit {
+	if err == nil {
+		return &sessionv1.SessionResponse_Exit{}
+	}
+	return &sessionv1.SessionResponse_Exit{
+		Code:    int32(WrapExitError(err).ExitCode()),
+		Message: err.Error(),
+	}
+}
+
+func exitToError(exit *sessionv1.SessionResponse_Exit) error {
+	if exit.GetCode() == 0 {
+		return nil
+	}
+	return NewExitError(int(exit.GetCode()), errors.New(exit.GetMessage()))
+}
@@ -15,11 +15,15 @@
 package pluginrpc
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 
 	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
+	"google.golang.org/protobuf/proto"
 )
 
 // Spec specifies a set of Procedures that a plugin implements. This describes
@@ -37,29 +41,62 @@ type Spec interface {
 	ProcedureForPath(path string) Procedure
 	// Procedures returns all Procedures.
 	Procedures() []Procedure
+	// Name returns the name of the plugin, if specified.
+	Name() string
+	// Version returns the semantic version of the plugin, if specified.
+	Version() string
+	// Description returns the description of the plugin, if specified.
+	Description() string
 
 	isSpec()
 }
 
 // NewSpec returns a new validated Spec for the given Procedures.
-func NewSpec(procedures []Procedure) (Spec, error) {
-	return newSpec(procedures)
+func NewSpec(procedures []Procedure, options ...SpecOption) (Spec, error) {
+	return newSpec(procedures, options...)
+}
+
+// SpecOption is an option for a new Spec.
+type SpecOption func(*specOptions)
+
+// SpecWithName specifies the name of the plugin.
+func SpecWithName(name string) SpecOption {
+	return func(specOptions *specOptions) {
+		specOptions.name = name
+	}
+}
+
+// SpecWithVersion specifies the version of the plugin.
+//
+// The version must be a semantic version as per https://semver.org, optionally prefixed
+// with a "v", for example "v1.2.3". Clients can require a minimum version with
+// ClientWithMinPluginVersion.
+func SpecWithVersion(version string) SpecOption {
+	return func(specOptions *specOptions) {
+		specOptions.version = version
+	}
+}
+
+// SpecWithDescription specifies a description of the plugin.
+//
+// Generated code uses the comments of the service as the description.
+func SpecWithDescription(description string) SpecOption {
+	return func(specOptions *specOptions) {
+		specOptions.description = description
+	}
 }
 
 // NewSpecForProto returns a new validated Spec for the given pluginrpcv1beta1.Spec.
+//
+// pluginrpcv1beta1.Specs only specify the path and args of each Procedure, so the returned
+// Spec has no name, version, or description. Clients retrieve these from plugins separately.
 func NewSpecForProto(protoSpec *pluginrpcv1beta1.Spec) (Spec, error) {
-	procedures := make([]Procedure, len(protoSpec.GetProcedures()))
-	for i, protoProcedure := range protoSpec.GetProcedures() {
-		procedure, err := NewProcedureForProto(protoProcedure)
-		if err != nil {
-			return nil, err
-		}
-		procedures[i] = procedure
-	}
-	return NewSpec(procedures)
+	return newSpecForProto(protoSpec, nil)
 }
 
 // NewProtoSpec returns a new pluginrpcv1beta1.Spec for the given Spec.
+//
+// pluginrpcv1beta1.Specs only specify the path and args of each Procedure. See NewSpecForProto.
 func NewProtoSpec(spec Spec) *pluginrpcv1beta1.Spec {
 	procedures := spec.Procedures()
 	protoProcedures := make([]*pluginrpcv1beta1.Procedure, len(procedures))
@@ -75,6 +112,9 @@ func NewProtoSpec(spec Spec) *pluginrpcv1beta1.Spec {
 //
 // Note that since the returns Spec is valid, this means that Procedures from the given
 // Specs must not contain any duplicate Procedures by path or args.
+//
+// The returned Spec has no name, version, or description. To specify these, pass the
+// Procedures of the returned Spec to NewSpec.
 func CombineSpecs(specs ...Spec) (Spec, error) {
 	var procedures []Procedure
 	for _, spec := range specs {
@@ -88,12 +128,24 @@ func CombineSpecs(specs ...Spec) (Spec, error) {
 type spec struct {
 	procedures      []Procedure
 	pathToProcedure map[string]Procedure
+	name            string
+	version         string
+	description     string
 }
 
-func newSpec(procedures []Procedure) (*spec, error) {
+func newSpec(procedures []Procedure, options ...SpecOption) (*spec, error) {
+	specOptions := newSpecOptions()
+	for _, option := range options {
+		option(specOptions)
+	}
 	if err := validateSpecProcedures(procedures); err != nil {
 		return nil, err
 	}
+	if specOptions.version != "" {
+		if _, err := parseSemver(specOptions.version); err != nil {
+			return nil, err
+		}
+	}
 	pathToProcedure := make(map[string]Procedure)
 	for _, procedure := range procedures {
 		pathToProcedure[procedure.Path()] = procedure
@@ -101,6 +153,9 @@ func newSpec(procedures []Procedure) (*spec, error) {
 	return &spec{
 		procedures:      procedures,
 		pathToProcedure: pathToProcedure,
+		name:            specOptions.name,
+		version:         specOptions.version,
+		description:     specOptions.description,
 	}, nil
 }
 
@@ -112,8 +167,143 @@ func (s *spec) Procedures() []Procedure {
 	return slices.Clone(s.procedures)
 }
 
+func (s *spec) Name() string {
+	return s.name
+}
+
+func (s *spec) Version() string {
+	return s.version
+}
+
+func (s *spec) Description() string {
+	return s.description
+}
+
 func (*spec) isSpec() {}
 
+// newSpecForProto returns a new validated Spec for the given pluginrpcv1beta1.Spec and the
+// Header sent alongside it by the plugin.
+//
+// The Header contains the properties of the Spec that pluginrpcv1beta1.Spec cannot represent.
+// The Header may be nil.
+func newSpecForProto(protoSpec *pluginrpcv1beta1.Spec, header Header) (Spec, error) {
+	idempotentPaths := make(map[string]struct{})
+	for _, path := range header.Values(headerKeyIdempotentProcedure) {
+		idempotentPaths[path] = struct{}{}
+	}
+	deprecatedPaths := make(map[string]struct{})
+	for _, path := range header.Values(headerKeyDeprecatedProcedure) {
+		deprecatedPaths[path] = struct{}{}
+	}
+	pathToStreamType := make(map[string]StreamType)
+	for _, path := range header.Values(headerKeyClientStreamProcedure) {
+		pathToStreamType[path] = StreamTypeClient
+	}
+	for _, path := range header.Values(headerKeyServerStreamProcedure) {
+		pathToStreamType[path] = StreamTypeServer
+	}
+	pathToDescription := make(map[string]string)
+	for _, value := range header.Values(headerKeyProcedureDescription) {
+		if path, description, ok := strings.Cut(value, "\n"); ok {
+			pathToDescription[path] = description
+		}
+	}
+	procedures := make([]Procedure, len(protoSpec.GetProcedures()))
+	for i, protoProcedure := range protoSpec.GetProcedures() {
+		path := protoProcedure.GetPath()
+		options := []ProcedureOption{
+			ProcedureWithArgs(protoProcedure.GetArgs()...),
+			ProcedureWithDescription(pathToDescription[path]),
+			ProcedureWithStreamType(pathToStreamType[path]),
+		}
+		if _, ok := idempotentPaths[path]; ok {
+			options = append(options, ProcedureWithIdempotent())
+		}
+		if _, ok := deprecatedPaths[path]; ok {
+			options = append(options, ProcedureWithDeprecated())
+		}
+		procedure, err := NewProcedure(path, options...)
+		if err != nil {
+			return nil, err
+		}
+		procedures[i] = procedure
+	}
+	return NewSpec(
+		procedures,
+		SpecWithName(header.Get(headerKeySpecName)),
+		SpecWithVersion(header.Get(headerKeySpecVersion)),
+		SpecWithDescription(header.Get(headerKeySpecDescription)),
+	)
+}
+
+// newSpecHeader returns the Header to send alongside the Spec.
+//
+// See newSpecForProto.
+func newSpecHeader(spec Spec, specID string) Header {
+	header := newSpecPropertiesHeader(spec)
+	header.Set(headerKeySpecID, specID)
+	return header
+}
+
+// newSpecPropertiesHeader returns a Header containing the properties of the Spec that
+// pluginrpcv1beta1.Spec cannot represent.
+func newSpecPropertiesHeader(spec Spec) Header {
+	header := make(Header)
+	for key, value := range map[string]string{
+		headerKeySpecName:        spec.Name(),
+		headerKeySpecVersion:     spec.Version(),
+		headerKeySpecDescription: spec.Description(),
+	} {
+		if value != "" {
+			header.Set(key, value)
+		}
+	}
+	for _, procedure := range spec.Procedures() {
+		if procedure.Idempotent() {
+			header.Add(headerKeyIdempotentProcedure, procedure.Path())
+		}
+		if procedure.Deprecated() {
+			header.Add(headerKeyDeprecatedProcedure, procedure.Path())
+		}
+		if description := procedure.Description(); description != "" {
+			header.Add(headerKeyProcedureDescription, procedure.Path()+"\n"+description)
+		}
+		switch procedure.StreamType() {
+		case StreamTypeClient:
+			header.Add(headerKeyClientStreamProcedure, procedure.Path())
+		case StreamTypeServer:
+			header.Add(headerKeyServerStreamProcedure, procedure.Path())
+		}
+	}
+	return header
+}
+
+// newSpecID returns the ID of the Spec.
+//
+// The ID is derived from the contents of the Spec, so that the ID changes if and only if
+// the Spec changes.
+func newSpecID(spec Spec) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(NewProtoSpec(spec))
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, _ = hash.Write(data)
+	// The properties that are not part of pluginrpcv1beta1.Spec are still part of the Spec.
+	header := newSpecPropertiesHeader(spec)
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			_, _ = hash.Write([]byte("\n" + key + ": " + strconv.Quote(value)))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16]), nil
+}
+
 func validateSpecProcedures(procedures []Procedure) error {
 	usedPathMap := make(map[string]struct{})
 	usedArgsMap := make(map[string]struct{})
@@ -136,3 +326,13 @@ func validateSpecProcedures(procedures []Procedure) error {
 	}
 	return nil
 }
+
+type specOptions struct {
+	name        string
+	version     string
+	description string
+}
+
+func newSpecOptions() *specOptions {
+	return &specOptions{}
+}
@@ -0,0 +1,415 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ResponseStream is a stream of responses returned from a server-streaming Procedure.
+//
+// ResponseStreams are returned from Client.CallServerStream. Typically, ResponseStreams
+// are not used directly. Instead, the generated code for a given service will wrap
+// the ResponseStream in a ServerStreamForClient.
+//
+// ResponseStreams are not safe for concurrent use.
+type ResponseStream interface {
+	// Receive receives the next response from the stream, and populates the given response.
+	//
+	// Returns io.EOF once all responses have been received. If the plugin returned an
+	// error, this error is returned, and all subsequent calls will return the same error.
+	// If the plugin returned a response alongside the error, the given response is
+	// populated, and HasPartialResponse returns true for the error.
+	Receive(response any) error
+	// Close closes the stream.
+	//
+	// If the plugin is still running, it will be cancelled. Close waits for the plugin
+	// to exit before returning. Close should always be called once the caller is done with
+	// the stream.
+	Close() error
+
+	isResponseStream()
+}
+
+// RequestStream is a stream of requests sent to a client-streaming Procedure.
+//
+// RequestStreams are returned from Client.CallClientStream. Typically, RequestStreams
+// are not used directly. Instead, the generated code for a given service will wrap
+// the RequestStream in a ClientStreamForClient.
+//
+// RequestStreams are not safe for concurrent use.
+type RequestStream interface {
+	// Send sends a request to the plugin.
+	//
+	// If the plugin has stopped reading requests, this returns io.EOF, and the
+	// error from the plugin can be retrieved by calling CloseAndReceive.
+	Send(request any) error
+	// CloseAndReceive closes the stream, and populates the given response.
+	//
+	// CloseAndReceive waits for the plugin to exit before returning.
+	CloseAndReceive(response any) error
+	// Close closes the stream without receiving a response, for example after Send returned
+	// an error.
+	//
+	// If the plugin is still running, it will be cancelled. Close waits for the plugin to
+	// exit before returning. Close is a no-op if CloseAndReceive was already called.
+	//
+	// One of Close or CloseAndReceive must always be called once the caller is done with
+	// the stream, otherwise the plugin invocation is leaked.
+	Close() error
+
+	isRequestStream()
+}
+
+// ServerStreamForClient is the client's view of a server-streaming Procedure.
+//
+// ServerStreamForClients are not safe for concurrent use.
+type ServerStreamForClient[Res any] struct {
+	responseStream ResponseStream
+}
+
+// NewServerStreamForClient returns a new ServerStreamForClient for the given ResponseStream.
+//
+// This is used within generated code.
+func NewServerStreamForClient[Res any](responseStream ResponseStream) *ServerStreamForClient[Res] {
+	return &ServerStreamForClient[Res]{
+		responseStream: responseStream,
+	}
+}
+
+// Receive receives the next response from the stream.
+//
+// Returns io.EOF once all responses have been received. If the plugin returned a response
+// alongside an error, the response is returned alongside the error, and HasPartialResponse
+// returns true for the error.
+func (s *ServerStreamForClient[Res]) Receive() (*Res, error) {
+	response := new(Res)
+	if err := s.responseStream.Receive(response); err != nil {
+		if HasPartialResponse(err) {
+			return response, err
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// Close closes the stream.
+//
+// Close should always be called once the caller is done with the stream.
+func (s *ServerStreamForClient[Res]) Close() error {
+	return s.responseStream.Close()
+}
+
+// ClientStreamForClient is the client's view of a client-streaming Procedure.
+//
+// ClientStreamForClients are not safe for concurrent use.
+type ClientStreamForClient[Req, Res any] struct {
+	requestStream RequestStream
+}
+
+// NewClientStreamForClient returns a new ClientStreamForClient for the given RequestStream.
+//
+// This is used within generated code.
+func NewClientStreamForClient[Req, Res any](requestStream RequestStream) *ClientStreamForClient[Req, Res] {
+	return &ClientStreamForClient[Req, Res]{
+		requestStream: requestStream,
+	}
+}
+
+// Send sends a request to the plugin.
+//
+// If the plugin has stopped reading requests, this returns io.EOF, and the
+// error from the plugin can be retrieved by calling CloseAndReceive.
+func (c *ClientStreamForClient[Req, Res]) Send(request *Req) error {
+	return c.requestStream.Send(request)
+}
+
+// CloseAndReceive closes the stream, and returns the response from the plugin.
+//
+// One of CloseAndReceive or Close must always be called once the caller is done with the
+// stream. If the plugin returned a response alongside an error, the response is returned
+// alongside the error, and HasPartialResponse returns true for the error.
+func (c *ClientStreamForClient[Req, Res]) CloseAndReceive() (*Res, error) {
+	response := new(Res)
+	if err := c.requestStream.CloseAndReceive(response); err != nil {
+		if HasPartialResponse(err) {
+			return response, err
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// Close closes the stream without receiving a response, cancelling the plugin if it is
+// still running.
+//
+// One of CloseAndReceive or Close must always be called once the caller is done with the
+// stream.
+func (c *ClientStreamForClient[Req, Res]) Close() error {
+	return c.requestStream.Close()
+}
+
+// ServerStream is the handler's view of a server-streaming Procedure.
+type ServerStream[Res any] struct {
+	send func(any) error
+}
+
+// NewServerStream returns a new ServerStream that uses the given function to send responses.
+//
+// This is used within generated code.
+func NewServerStream[Res any](send func(any) error) *ServerStream[Res] {
+	return &ServerStream[Res]{
+		send: send,
+	}
+}
+
+// Send sends a response to the client.
+func (s *ServerStream[Res]) Send(response *Res) error {
+	return s.send(response)
+}
+
+// ClientStream is the handler's view of a client-streaming Procedure.
+type ClientStream[Req any] struct {
+	receive func(any) error
+}
+
+// NewClientStream returns a new ClientStream that uses the given function to receive requests.
+//
+// This is used within generated code.
+func NewClientStream[Req any](receive func(any) error) *ClientStream[Req] {
+	return &ClientStream[Req]{
+		receive: receive,
+	}
+}
+
+// Receive receives the next request from the client.
+//
+// Returns io.EOF once all requests have been received.
+func (c *ClientStream[Req]) Receive() (*Req, error) {
+	request := new(Req)
+	if err := c.receive(request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// *** PRIVATE ***
+
+type responseStream struct {
+	readCloser  io.ReadCloser
+	frameReader *frameReader
+	cancel      context.CancelFunc
+	done        <-chan struct{}
+	onHeader    func(Header) error
+	validator   Validator
+
+	readHeader bool
+	err        error
+	closeOnce  sync.Once
+}
+
+// newResponseStream returns a new responseStream.
+//
+// The readCloser is expected to return the wrapped error from the plugin invocation, if any,
+// once all frames have been read. The cancel function will cancel the plugin invocation, and
+// done will be closed once the plugin invocation has completed. The onHeader function is
+// called with the Header sent by the plugin, if any, before the first response is returned.
+// If onHeader returns an error, the stream is terminated with the error. Responses are
+// validated with the Validator, if any.
+func newResponseStream(
+	readCloser io.ReadCloser,
+	cancel context.CancelFunc,
+	done <-chan struct{},
+	onHeader func(Header) error,
+	validator Validator,
+) *responseStream {
+	return &responseStream{
+		readCloser:  readCloser,
+		frameReader: newFrameReader(readCloser),
+		cancel:      cancel,
+		done:        done,
+		onHeader:    onHeader,
+		validator:   validator,
+	}
+}
+
+func (r *responseStream) Receive(response any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.readHeader {
+		r.readHeader = true
+		header, err := r.frameReader.NextHeader()
+		if err != nil {
+			r.err = err
+			return err
+		}
+		if err := r.onHeader(header); err != nil {
+			r.err = err
+			return err
+		}
+	}
+	data, format, err := r.frameReader.Next()
+	if err != nil {
+		r.err = err
+		return err
+	}
+	if err := unmarshalResponse(format, data, response, nil); err != nil {
+		// Either the frame was malformed or the plugin returned an error. Either way,
+		// the stream is terminated.
+		r.err = err
+		return err
+	}
+	if err := validateMessage(r.validator, response, CodeInternal); err != nil {
+		r.err = err
+		return err
+	}
+	return nil
+}
+
+func (r *responseStream) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		// Closing the reader unblocks any pending writes from the plugin.
+		err = r.readCloser.Close()
+		r.cancel()
+		<-r.done
+	})
+	return err
+}
+
+func (*responseStream) isResponseStream() {}
+
+type requestStream struct {
+	writeCloser io.WriteCloser
+	format      format
+	headerData  []byte
+	stdout      *bytes.Buffer
+	cancel      context.CancelFunc
+	done        <-chan error
+	onHeader    func(Header) error
+	validator   Validator
+
+	wroteHeader bool
+	closed      bool
+}
+
+// newRequestStream returns a new requestStream.
+//
+// The writeCloser is expected to return io.EOF once the plugin has exited. Requests are
+// written in the given format, preceded by the given header frame data, if any. The stdout
+// buffer must not be read until done has returned the wrapped error from the plugin
+// invocation. The cancel function will cancel the plugin invocation. The onHeader function is called with the Header sent by the plugin, if any,
+// and if onHeader returns an error, the error is returned from CloseAndReceive. Requests and
+// the response are validated with the Validator, if any.
+func newRequestStream(
+	writeCloser io.WriteCloser,
+	format format,
+	headerData []byte,
+	stdout *bytes.Buffer,
+	cancel context.CancelFunc,
+	done <-chan error,
+	onHeader func(Header) error,
+	validator Validator,
+) *requestStream {
+	return &requestStream{
+		writeCloser: writeCloser,
+		format:      format,
+		headerData:  headerData,
+		stdout:      stdout,
+		cancel:      cancel,
+		done:        done,
+		onHeader:    onHeader,
+		validator:   validator,
+	}
+}
+
+func (r *requestStream) Send(request any) error {
+	if r.closed {
+		return errors.New("send called on closed stream")
+	}
+	if err := validateMessage(r.validator, request, CodeInvalidArgument); err != nil {
+		return err
+	}
+	data, err := marshalRequest(r.format, request, nil)
+	if err != nil {
+		return err
+	}
+	if err := r.writeHeader(); err != nil {
+		return err
+	}
+	return writeFrame(r.writeCloser, r.format, data)
+}
+
+func (r *requestStream) CloseAndReceive(response any) error {
+	if r.closed {
+		return errors.New("stream already closed")
+	}
+	r.closed = true
+	// If the plugin has already exited, the header cannot be written, and the error
+	// from the plugin is returned below.
+	_ = r.writeHeader()
+	if err := r.writeCloser.Close(); err != nil {
+		return err
+	}
+	if err := <-r.done; err != nil {
+		return err
+	}
+	header, data, err := decodeHeaderFrame(r.stdout.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := r.onHeader(header); err != nil {
+		return err
+	}
+	if err := unmarshalResponseFrame(data, response, nil); err != nil {
+		return err
+	}
+	return validateMessage(r.validator, response, CodeInternal)
+}
+
+func (r *requestStream) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.writeCloser.Close()
+	r.cancel()
+	// The error from the plugin is discarded, as the plugin was cancelled.
+	<-r.done
+	return err
+}
+
+// writeHeader writes the header frame, if any, if it has not already been written.
+//
+// The header frame is written lazily, so that creating a stream does not block on the
+// plugin reading stdin.
+func (r *requestStream) writeHeader() error {
+	if r.wroteHeader {
+		return nil
+	}
+	r.wroteHeader = true
+	if len(r.headerData) == 0 {
+		return nil
+	}
+	_, err := r.writeCloser.Write(r.headerData)
+	return err
+}
+
+func (*requestStream) isRequestStream() {}
@@ -0,0 +1,55 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import "errors"
+
+// Validator validates requests and responses.
+//
+// Validators are given to Handlers with HandlerWithValidator, and to Clients with
+// ClientWithValidator. The protovalidate package provides a Validator that enforces
+// buf.validate annotations.
+type Validator interface {
+	// Validate returns an error if the given request or response is invalid.
+	//
+	// The error is returned as an *Error with CodeInvalidArgument for requests, and with
+	// CodeInternal for responses, as an invalid response indicates a bug in the plugin.
+	// If the error is an *Error, its ErrorDetails are kept.
+	Validate(message any) error
+}
+
+// *** PRIVATE ***
+
+// validateMessage validates the request or response with the Validator, if any.
+//
+// Errors are returned as *Errors with the given Code. Nil messages are not validated.
+func validateMessage(validator Validator, message any, code Code) error {
+	if validator == nil || isNilResponse(message) {
+		return nil
+	}
+	err := validator.Validate(message)
+	if err == nil {
+		return nil
+	}
+	pluginrpcError := &Error{}
+	if !errors.As(err, &pluginrpcError) {
+		return NewError(code, err)
+	}
+	validationError := NewError(code, pluginrpcError.Unwrap())
+	for _, errorDetail := range pluginrpcError.Details() {
+		validationError.AddDetail(errorDetail)
+	}
+	return validationError
+}